@@ -0,0 +1,255 @@
+package cue
+
+import (
+  "io"
+  "os"
+  "fmt"
+  "time"
+  "bufio"
+  "strings"
+  "strconv"
+)
+
+// cue sheet positions are in CD frames, 75 per second
+const FramesPerSecond = 75
+
+// position within a FILE in frames
+type Time int
+
+// parse mm:ss:ff
+func ParseTime(s string) (Time, error) {
+  p := strings.Split(s, ":")
+  if len(p) != 3 {
+    return 0, fmt.Errorf("invalid time %q", s)
+  }
+
+  n := make([]int, 3)
+  for i := range p {
+    v, err := strconv.Atoi(p[i])
+    if err != nil || v < 0 {
+      return 0, fmt.Errorf("invalid time %q", s)
+    }
+    n[i] = v
+  }
+
+  if n[1] > 59 || n[2] >= FramesPerSecond {
+    return 0, fmt.Errorf("invalid time %q", s)
+  }
+  return Time((n[0]*60 + n[1]) * FramesPerSecond + n[2]), nil
+}
+
+func (t Time) Duration() time.Duration {
+  return time.Duration(t) * time.Second / FramesPerSecond
+}
+
+// exact sample offset at sample rate
+func (t Time) Samples(rate int) int64 {
+  return int64(t) * int64(rate) / FramesPerSecond
+}
+
+func (t Time) String() string {
+  return fmt.Sprintf("%02d:%02d:%02d", t / FramesPerSecond / 60,
+    t / FramesPerSecond % 60, t % FramesPerSecond)
+}
+
+type Sheet struct {
+  Performer, Title, Songwriter string
+  // from REM DATE, REM GENRE, REM DISCNUMBER
+  Date, Genre, Disc string
+  // all REM entries keyed by upper case name
+  Rem map[string]string
+  // FILE entries in order
+  Files []string
+  Tracks []*Track
+}
+
+type Track struct {
+  Number int
+  Type string
+  Title, Performer, Songwriter, Isrc string
+  // FILE containing INDEX 01
+  File string
+  // INDEX number to position, 00 is pregap & 01 is track start
+  Index map[int]Time
+}
+
+// track start (INDEX 01)
+func (t *Track) Start() Time {
+  return t.Index[1]
+}
+
+// end of track i: start of next track within the same FILE, false if the
+// track runs to the end of its FILE
+func (s *Sheet) End(i int) (Time, bool) {
+  if i+1 < len(s.Tracks) && s.Tracks[i+1].File == s.Tracks[i].File {
+    return s.Tracks[i+1].Start(), true
+  }
+  return 0, false
+}
+
+// parse cue sheet from file
+func ParseFile(path string) (*Sheet, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+
+  return Parse(f)
+}
+
+// parse cue sheet
+func Parse(r io.Reader) (*Sheet, error) {
+  s := &Sheet{ Rem: map[string]string{} }
+
+  var file string
+  var track *Track
+
+  scanner := bufio.NewScanner(r)
+  for n := 1; scanner.Scan(); n++ {
+    line := scanner.Text()
+    if n == 1 {
+      line = strings.TrimPrefix(line, "\ufeff")
+    }
+
+    f := fields(line)
+    if len(f) == 0 {
+      continue
+    }
+
+    err := s.command(strings.ToUpper(f[0]), f[1:], &file, &track)
+    if err != nil {
+      return nil, fmt.Errorf("cue line %d: %v", n, err)
+    }
+  }
+
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+
+  for _, t := range s.Tracks {
+    if _, ok := t.Index[1]; !ok {
+      return nil, fmt.Errorf("cue track %d: missing INDEX 01", t.Number)
+    }
+  }
+  return s, nil
+}
+
+// apply a single cue command
+func (s *Sheet) command(cmd string, args []string, file *string,
+  track **Track) error {
+
+  arg := func(i int) string {
+    if i < len(args) {
+      return args[i]
+    }
+    return ""
+  }
+
+  switch cmd {
+  case "REM":
+    if len(args) == 0 {
+      return nil
+    }
+    k, v := strings.ToUpper(args[0]), strings.Join(args[1:], " ")
+    s.Rem[k] = v
+
+    switch k {
+    case "DATE":
+      s.Date = v
+    case "GENRE":
+      s.Genre = v
+    case "DISCNUMBER":
+      s.Disc = v
+    }
+  case "FILE":
+    if len(args) == 0 {
+      return fmt.Errorf("FILE requires a file name")
+    }
+    *file = args[0]
+    s.Files = append(s.Files, *file)
+  case "TRACK":
+    if len(*file) == 0 {
+      return fmt.Errorf("TRACK before FILE")
+    }
+    num, err := strconv.Atoi(arg(0))
+    if err != nil {
+      return fmt.Errorf("invalid track number %q", arg(0))
+    }
+    *track = &Track{ Number: num, Type: strings.ToUpper(arg(1)),
+      File: *file, Index: map[int]Time{} }
+    s.Tracks = append(s.Tracks, *track)
+  case "INDEX":
+    if *track == nil {
+      return fmt.Errorf("INDEX before TRACK")
+    }
+    num, err := strconv.Atoi(arg(0))
+    if err != nil {
+      return fmt.Errorf("invalid index number %q", arg(0))
+    }
+    t, err := ParseTime(arg(1))
+    if err != nil {
+      return err
+    }
+    (*track).Index[num] = t
+    // track starts in the FILE in effect at INDEX 01
+    if num == 1 {
+      (*track).File = *file
+    }
+  case "TITLE", "PERFORMER", "SONGWRITER":
+    s.text(cmd, arg(0), *track)
+  case "ISRC":
+    if *track != nil {
+      (*track).Isrc = arg(0)
+    }
+  }
+
+  return nil
+}
+
+// set TITLE, PERFORMER or SONGWRITER on track, or sheet if before first TRACK
+func (s *Sheet) text(cmd, v string, t *Track) {
+  var title, performer, songwriter *string
+  if t != nil {
+    title, performer, songwriter = &t.Title, &t.Performer, &t.Songwriter
+  } else {
+    title, performer, songwriter = &s.Title, &s.Performer, &s.Songwriter
+  }
+
+  switch cmd {
+  case "TITLE":
+    *title = v
+  case "PERFORMER":
+    *performer = v
+  case "SONGWRITER":
+    *songwriter = v
+  }
+}
+
+// split line on whitespace, keeping double quoted strings intact
+func fields(line string) []string {
+  f := []string{}
+  var cur strings.Builder
+  quoted, started := false, false
+
+  for _, r := range strings.TrimSpace(line) {
+    switch {
+    case r == '"':
+      quoted, started = !quoted, true
+    case !quoted && (r == ' ' || r == '\t'):
+      if started {
+        f = append(f, cur.String())
+        cur.Reset()
+        started = false
+      }
+    default:
+      cur.WriteRune(r)
+      started = true
+    }
+  }
+
+  if started {
+    f = append(f, cur.String())
+  }
+  return f
+}
@@ -0,0 +1,107 @@
+package cue
+
+import (
+  "strings"
+  "testing"
+)
+
+var testSheet = "\ufeff" + `REM GENRE Rock
+REM DATE 1977-05-08
+REM DISCNUMBER 1
+PERFORMER "Grateful Dead"
+TITLE "Barton Hall, Cornell University"
+FILE "gd77-05-08d1.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "New Minglewood Blues"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Loser"
+    PERFORMER "Jerry Garcia"
+    INDEX 00 05:49:12
+    INDEX 01 05:51:00
+  TRACK 03 AUDIO
+    TITLE "El Paso"
+FILE "gd77-05-08d2.flac" WAVE
+    INDEX 01 00:00:33
+  TRACK 04 AUDIO
+    TITLE "They Love Each Other"
+    INDEX 01 04:30:74
+`
+
+func TestParse(t *testing.T) {
+  s, err := Parse(strings.NewReader(strings.Replace(testSheet, "\n", "\r\n", -1)))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if s.Performer != "Grateful Dead" || s.Title != "Barton Hall, Cornell University" ||
+    s.Date != "1977-05-08" || s.Genre != "Rock" || s.Disc != "1" {
+    t.Errorf("Unexpected sheet fields %+v", s)
+  }
+
+  if strings.Join(s.Files, ",") != "gd77-05-08d1.flac,gd77-05-08d2.flac" {
+    t.Errorf("Unexpected files %v", s.Files)
+  }
+
+  tests := []struct {
+    number int
+    title, performer, file, start string
+    end string
+  }{
+    { 1, "New Minglewood Blues", "", "gd77-05-08d1.flac", "00:00:00", "05:51:00" },
+    { 2, "Loser", "Jerry Garcia", "gd77-05-08d1.flac", "05:51:00", "" },
+    { 3, "El Paso", "", "gd77-05-08d2.flac", "00:00:33", "04:30:74" },
+    { 4, "They Love Each Other", "", "gd77-05-08d2.flac", "04:30:74", "" },
+  }
+
+  if len(s.Tracks) != len(tests) {
+    t.Fatalf("Expected %v tracks, got %v", len(tests), len(s.Tracks))
+  }
+
+  for i, e := range tests {
+    tr := s.Tracks[i]
+    if tr.Number != e.number || tr.Title != e.title ||
+      tr.Performer != e.performer || tr.File != e.file ||
+      tr.Start().String() != e.start {
+      t.Errorf("Expected %+v, got %+v", e, tr)
+    }
+
+    end, ok := s.End(i)
+    if ok != (e.end != "") || (ok && end.String() != e.end) {
+      t.Errorf("Track %v: expected end %q, got %v %v", e.number, e.end, end, ok)
+    }
+  }
+
+  if s.Tracks[1].Index[0].String() != "05:49:12" {
+    t.Errorf("Expected pregap 05:49:12, got %v", s.Tracks[1].Index[0])
+  }
+}
+
+func TestParseErrors(t *testing.T) {
+  tests := []string{
+    "TRACK 01 AUDIO\n",
+    "FILE \"a.wav\" WAVE\nINDEX 01 00:00:00\n",
+    "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:60:00\n",
+    "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:00\n",
+  }
+
+  for i := range tests {
+    _, err := Parse(strings.NewReader(tests[i]))
+    if err == nil {
+      t.Errorf("Expected error for %q", tests[i])
+    }
+  }
+}
+
+func TestTime(t *testing.T) {
+  tm, err := ParseTime("05:51:74")
+  if err != nil {
+    t.Fatal(err)
+  }
+  if tm.Samples(44100) != 15522612 {
+    t.Errorf("Expected 15522612, got %v", tm.Samples(44100))
+  }
+  if tm.String() != "05:51:74" {
+    t.Errorf("Expected 05:51:74, got %v", tm)
+  }
+}
@@ -0,0 +1,165 @@
+package ffmpeg
+
+import (
+  "os"
+  "fmt"
+  "context"
+  "strconv"
+  "strings"
+  "path/filepath"
+)
+
+type ArtworkConfig struct {
+  // longest side in pixels, 500 if 0. images are never upscaled
+  MaxSize int
+  // "jpeg" or "png", from output extension if empty
+  Format string
+  // jpeg quality from 2 (best) to 31 (-qscale:v), 2 if 0
+  Quality int
+  // make square by "crop" (centered) or "pad" (black bars), unchanged if empty
+  Square string
+  // largest output in bytes, 0 for no limit. jpeg quality is lowered until
+  // the image fits
+  MaxBytes int64
+}
+
+// resize & re-encode image for embedding as album art
+func (f *ffmpeg) OptimizeArtwork(input, output string,
+  c *ArtworkConfig) (string, error) {
+
+  return f.OptimizeArtworkContext(context.Background(), input, output, c)
+}
+
+// optimize image for embedding, aborting if ctx is done
+func (f *ffmpeg) OptimizeArtworkContext(ctx context.Context, input,
+  output string, c *ArtworkConfig) (string, error) {
+
+  if c == nil {
+    c = &ArtworkConfig{}
+  }
+
+  a := *c
+  if a.MaxSize <= 0 {
+    a.MaxSize = 500
+  }
+  if a.Quality <= 0 {
+    a.Quality = 2
+  }
+  if len(a.Format) == 0 {
+    a.Format = "jpeg"
+    if strings.ToLower(filepath.Ext(output)) == ".png" {
+      a.Format = "png"
+    }
+  }
+
+  if a.Format != "jpeg" && a.Format != "png" {
+    return "", fmt.Errorf("unsupported artwork format %q", a.Format)
+  }
+  if a.Quality > 31 {
+    return "", fmt.Errorf("jpeg quality must be 2-31")
+  }
+
+  for {
+    args, err := optimizeArtArgs(input, output, &a)
+    if err != nil {
+      return "", err
+    }
+
+    s, err := f.ExecContext(ctx, args...)
+    if err != nil || a.MaxBytes <= 0 || f.dryRun != nil {
+      return s, err
+    }
+
+    info, err := os.Stat(output)
+    if err != nil {
+      return s, err
+    }
+    if info.Size() <= a.MaxBytes {
+      return s, nil
+    }
+
+    // png is lossless, only jpeg can trade quality for size
+    if a.Format == "png" || a.Quality >= 31 {
+      os.Remove(output)
+      return s, fmt.Errorf("artwork is %d bytes, larger than %d",
+        info.Size(), a.MaxBytes)
+    }
+
+    a.Quality += 3
+    if a.Quality > 31 {
+      a.Quality = 31
+    }
+  }
+}
+
+// build ffmpeg arguments for config with defaults applied
+func optimizeArtArgs(input, output string, c *ArtworkConfig) ([]string, error) {
+  filters := []string{}
+
+  switch c.Square {
+  case "":
+  case "crop":
+    filters = append(filters, "crop=min(iw\\,ih):min(iw\\,ih)")
+  case "pad":
+    filters = append(filters,
+      "pad=max(iw\\,ih):max(iw\\,ih):(ow-iw)/2:(oh-ih)/2:color=black")
+  default:
+    return []string{}, fmt.Errorf("unsupported square mode %q", c.Square)
+  }
+
+  // fit within max size box without upscaling
+  filters = append(filters, fmt.Sprintf("scale=min(iw\\,%d):min(ih\\,%d):" +
+    "force_original_aspect_ratio=decrease", c.MaxSize, c.MaxSize))
+
+  a := []string{ "-i", input, "-vf", strings.Join(filters, ","),
+    "-frames:v", "1" }
+  if c.Format == "png" {
+    a = append(a, "-c:v", "png")
+  } else {
+    a = append(a, "-c:v", "mjpeg", "-qscale:v", strconv.Itoa(c.Quality))
+  }
+
+  return append(a, "-f", "image2", "-update", "1", "-y", output), nil
+}
+
+// embedded artwork details
+type Picture struct {
+  // image codec: mjpeg or png
+  Codec string
+  // picture type, e.g. "Cover (front)", empty if unknown
+  Type string
+  Width, Height int
+}
+
+// write embedded album art of input to output without re-encoding. output
+// keeps the picture's native format regardless of its extension
+func (f *ffmpeg) ExtractAlbumArt(input, output string) (*Picture, error) {
+  return f.ExtractAlbumArtContext(context.Background(), input, output)
+}
+
+// extract embedded album art, aborting if ctx is done
+func (f *ffmpeg) ExtractAlbumArtContext(ctx context.Context,
+  input, output string) (*Picture, error) {
+
+  s, err := pictureStream(ctx, input)
+  if err != nil {
+    return nil, err
+  }
+
+  _, err = f.ExecContext(ctx, extractArtArgs(input, output, s.Index)...)
+  if err != nil {
+    return nil, err
+  }
+
+  p := &Picture{ Codec: s.CodecName, Width: s.Width, Height: s.Height }
+  if s.Tags != nil {
+    p.Type = s.Tags.Comment
+  }
+  return p, nil
+}
+
+func extractArtArgs(input, output string, stream int) []string {
+  return []string{ "-i", input, "-map", "0:" + strconv.Itoa(stream),
+    "-c", "copy", "-frames:v", "1", "-f", "image2", "-update", "1",
+    "-y", output }
+}
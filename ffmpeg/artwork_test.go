@@ -0,0 +1,56 @@
+package ffmpeg
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestOptimizeArtArgs(t *testing.T) {
+  tests := []struct {
+    config *ArtworkConfig
+    args string
+  }{
+    { &ArtworkConfig{ MaxSize: 500, Quality: 2, Format: "jpeg" },
+      `-i in.png -vf scale=min(iw\,500):min(ih\,500):` +
+      `force_original_aspect_ratio=decrease -frames:v 1 -c:v mjpeg ` +
+      `-qscale:v 2 -f image2 -update 1 -y out.jpg` },
+    { &ArtworkConfig{ MaxSize: 600, Format: "png", Square: "crop" },
+      `-i in.png -vf crop=min(iw\,ih):min(iw\,ih),scale=min(iw\,600):` +
+      `min(ih\,600):force_original_aspect_ratio=decrease -frames:v 1 ` +
+      `-c:v png -f image2 -update 1 -y out.jpg` },
+  }
+
+  for i := range tests {
+    a, err := optimizeArtArgs("in.png", "out.jpg", tests[i].config)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if strings.Join(a, " ") != tests[i].args {
+      t.Errorf("Expected %v, got %v", tests[i].args, strings.Join(a, " "))
+    }
+  }
+
+  _, err := optimizeArtArgs("in.png", "out.jpg", &ArtworkConfig{ Square: "x" })
+  if err == nil {
+    t.Errorf("Expected error for unsupported square mode")
+  }
+}
+
+func TestOptimizeArtworkDefaults(t *testing.T) {
+  d := NewDryRun()
+  d.Bin = "ffmpeg"
+
+  // nil config uses defaults
+  if _, err := d.OptimizeArtwork("in.png", "out.jpg", nil); err != nil {
+    t.Fatal(err)
+  }
+
+  c := d.Commands()
+  if len(c) != 1 {
+    t.Fatalf("Expected 1 command, got %d", len(c))
+  }
+  if a := strings.Join(c[0], " "); !strings.Contains(a, "min(iw\\,500)") ||
+    !strings.Contains(a, "-qscale:v 2") {
+    t.Errorf("Expected default size & quality, got %v", a)
+  }
+}
@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+  "os"
+  "context"
+  "io/ioutil"
+  "path/filepath"
+)
+
+// unique temp path next to path (same directory & extension) so it can be
+// renamed over path atomically. the name is reserved then released so
+// ffmpeg creates the file with default permissions
+func tempFileFor(path string) (string, error) {
+  dir, file := filepath.Split(path)
+  ext := filepath.Ext(file)
+
+  tmp, err := ioutil.TempFile(dir, "." + file[:len(file)-len(ext)] + "-*" + ext)
+  if err != nil {
+    return "", err
+  }
+  tmp.Close()
+  return tmp.Name(), os.Remove(tmp.Name())
+}
+
+// call fn to write a temp file next to output, renaming it to output only
+// if fn succeeds. temp file is removed on any error. a dry run records the
+// rename instead
+func (f *ffmpeg) writeAtomic(output string, fn func(tmp string) error) error {
+  tmp, err := f.tempFileFor(output)
+  if err != nil {
+    return err
+  }
+
+  err = fn(tmp)
+  if err == nil {
+    err = f.rename(tmp, output)
+  }
+  if err != nil && f.dryRun == nil {
+    os.Remove(tmp)
+  }
+  return err
+}
+
+// rewrite path in place: run ffmpeg with args built for a temp output, then
+// rename temp over path keeping its file mode, and modification time if
+// keepTime. temp file is removed on any error
+func (f *ffmpeg) rewrite(ctx context.Context, path string, keepTime bool,
+  args func(tmp string) []string) error {
+
+  info, err := os.Stat(path)
+  if err != nil {
+    return err
+  }
+
+  return f.writeAtomic(path, func(tmp string) error {
+    _, err := f.ExecContext(ctx, args(tmp)...)
+    if err != nil || f.dryRun != nil {
+      return err
+    }
+
+    err = os.Chmod(tmp, info.Mode())
+    if err == nil && keepTime {
+      err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+    }
+    return err
+  })
+}
@@ -0,0 +1,178 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "sync"
+  "errors"
+  "context"
+  "runtime"
+)
+
+// single conversion within a batch, set one of Mp3 or Transcode
+type Job struct {
+  Mp3 *Mp3Config
+  Transcode *TranscodeConfig
+}
+
+type EventType int
+
+const (
+  EventStarted EventType = iota
+  EventProgress
+  EventFinished
+  EventFailed
+)
+
+func (e EventType) String() string {
+  switch e {
+  case EventStarted:
+    return "started"
+  case EventProgress:
+    return "progress"
+  case EventFinished:
+    return "finished"
+  }
+  return "failed"
+}
+
+// job state change, Job is the index into Batch.Jobs
+type Event struct {
+  Type EventType
+  Job int
+  // set for EventProgress
+  Progress *Progress
+  // set for EventFailed
+  Err error
+}
+
+// outcome of a job, in the same order as Batch.Jobs
+type Result struct {
+  Job int
+  Output string
+  Err error
+}
+
+type Batch struct {
+  Jobs []*Job
+  // jobs run at once, runtime.NumCPU() if 0
+  Parallel int
+  // receives job events when set, closed once all jobs are done. events are
+  // dropped once ctx is done so an abandoned channel can not block the batch
+  Events chan<- Event
+}
+
+// converts batch jobs, implemented by New, NewDryRun & MockFfmpeg
+type Transcoder interface {
+  ToMp3Context(ctx context.Context, c *Mp3Config) (string, error)
+  TranscodeContext(ctx context.Context, c *TranscodeConfig) (string, error)
+}
+
+// run batch jobs through f with bounded parallelism. jobs not yet started
+// when ctx is done fail with ctx.Err(). error is non-nil if any job failed
+func RunBatch(ctx context.Context, f Transcoder, b *Batch) ([]Result, error) {
+  results := make([]Result, len(b.Jobs))
+
+  n := b.Parallel
+  if n <= 0 {
+    n = runtime.NumCPU()
+  }
+
+  emit := func(e Event) {
+    if b.Events == nil {
+      return
+    }
+    select {
+    case b.Events <- e:
+    case <-ctx.Done():
+    }
+  }
+
+  jobs := make(chan int)
+  var wg sync.WaitGroup
+
+  for w := 0; w < n; w++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for i := range jobs {
+        results[i] = runJob(ctx, f, i, b.Jobs[i], b.Events != nil, emit)
+      }
+    }()
+  }
+
+  for i := range b.Jobs {
+    jobs <- i
+  }
+  close(jobs)
+  wg.Wait()
+
+  if b.Events != nil {
+    close(b.Events)
+  }
+
+  failed := 0
+  for i := range results {
+    if results[i].Err != nil {
+      failed++
+    }
+  }
+  if failed > 0 {
+    return results, fmt.Errorf("%d of %d jobs failed", failed, len(results))
+  }
+  return results, nil
+}
+
+// run a single job emitting its events. progress is only reported when
+// events are watched, avoiding progress output & a duration probe otherwise
+func runJob(ctx context.Context, f Transcoder, i int, j *Job, events bool,
+  emit func(e Event)) Result {
+
+  r := Result{ Job: i }
+
+  fail := func(err error) Result {
+    r.Err = err
+    emit(Event{ Type: EventFailed, Job: i, Err: err })
+    return r
+  }
+
+  if err := ctx.Err(); err != nil {
+    return fail(err)
+  }
+  if j == nil || (j.Mp3 == nil && j.Transcode == nil) {
+    return fail(errors.New("job has no configuration"))
+  }
+
+  emit(Event{ Type: EventStarted, Job: i })
+
+  // report progress as events, still calling any job callback
+  progress := func(fn ProgressFunc) ProgressFunc {
+    if !events {
+      return fn
+    }
+    return func(p *Progress) {
+      if fn != nil {
+        fn(p)
+      }
+      emit(Event{ Type: EventProgress, Job: i, Progress: p })
+    }
+  }
+
+  if j.Mp3 != nil {
+    c := *j.Mp3
+    c.Progress = progress(c.Progress)
+    _, r.Err = f.ToMp3Context(ctx, &c)
+    r.Output = c.Output
+  } else {
+    c := *j.Transcode
+    c.Progress = progress(c.Progress)
+    _, r.Err = f.TranscodeContext(ctx, &c)
+    r.Output = c.Output
+  }
+
+  if r.Err != nil {
+    return fail(r.Err)
+  }
+
+  emit(Event{ Type: EventFinished, Job: i })
+  return r
+}
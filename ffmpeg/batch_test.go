@@ -0,0 +1,118 @@
+package ffmpeg
+
+import (
+  "os"
+  "sync"
+  "context"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestRunBatch(t *testing.T) {
+  dir, err := ioutil.TempDir("", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  b := &Batch{ Parallel: 2, Jobs: []*Job{
+    { Mp3: &Mp3Config{ Input: "1.flac", Output: filepath.Join(dir, "1.mp3") } },
+    { Transcode: &TranscodeConfig{ Input: "2.flac",
+      Output: filepath.Join(dir, "2.opus"), Codec: CodecOpus } },
+    { Mp3: &Mp3Config{ Input: "3.flac", Output: filepath.Join(dir, "dne", "3.mp3") } },
+    {},
+    nil,
+  } }
+
+  events := make(chan Event)
+  b.Events = events
+
+  counts := map[EventType]int{}
+  done := make(chan bool)
+  go func() {
+    for e := range events {
+      counts[e.Type]++
+    }
+    done <- true
+  }()
+
+  results, err := RunBatch(context.Background(), &MockFfmpeg{}, b)
+  <-done
+
+  if err == nil {
+    t.Errorf("Expected error for failed jobs")
+  }
+
+  for i, fail := range []bool{ false, false, true, true, true } {
+    if results[i].Job != i || (results[i].Err != nil) != fail {
+      t.Errorf("Job %v: unexpected result %+v", i, results[i])
+    }
+  }
+
+  if counts[EventStarted] != 3 || counts[EventFinished] != 2 ||
+    counts[EventFailed] != 3 {
+    t.Errorf("Unexpected event counts %v", counts)
+  }
+
+  // canceled before start
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+
+  results, _ = RunBatch(ctx, &MockFfmpeg{}, &Batch{ Jobs: b.Jobs[:1] })
+  if results[0].Err != context.Canceled {
+    t.Errorf("Expected %v, got %v", context.Canceled, results[0].Err)
+  }
+}
+
+// records whether each job was given a progress callback
+type progressTranscoder struct {
+  sync.Mutex
+  progress map[string]bool
+}
+
+func (p *progressTranscoder) ToMp3Context(ctx context.Context,
+  c *Mp3Config) (string, error) {
+
+  p.Lock()
+  p.progress[c.Input] = c.Progress != nil
+  p.Unlock()
+  return c.Output, nil
+}
+
+func (p *progressTranscoder) TranscodeContext(ctx context.Context,
+  c *TranscodeConfig) (string, error) {
+
+  p.Lock()
+  p.progress[c.Input] = c.Progress != nil
+  p.Unlock()
+  return c.Output, nil
+}
+
+func TestRunBatchProgress(t *testing.T) {
+  b := &Batch{ Jobs: []*Job{
+    { Mp3: &Mp3Config{ Input: "1.flac", Output: "1.mp3" } },
+    { Transcode: &TranscodeConfig{ Input: "2.flac", Output: "2.opus",
+      Progress: func(p *Progress) {} } },
+  } }
+
+  // without events only a job's own callback is kept
+  f := &progressTranscoder{ progress: map[string]bool{} }
+  if _, err := RunBatch(context.Background(), f, b); err != nil {
+    t.Fatal(err)
+  }
+  if f.progress["1.flac"] || !f.progress["2.flac"] {
+    t.Errorf("Unexpected progress callbacks %v", f.progress)
+  }
+
+  events := make(chan Event, 16)
+  b.Events = events
+
+  f = &progressTranscoder{ progress: map[string]bool{} }
+  if _, err := RunBatch(context.Background(), f, b); err != nil {
+    t.Fatal(err)
+  }
+  if !f.progress["1.flac"] || !f.progress["2.flac"] {
+    t.Errorf("Expected progress callbacks with events, got %v", f.progress)
+  }
+}
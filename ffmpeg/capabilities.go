@@ -0,0 +1,255 @@
+package ffmpeg
+
+import (
+  "bytes"
+  "context"
+  "strings"
+)
+
+// encoders, muxers & filters of an ffmpeg build
+type Capabilities struct {
+  // version string, e.g. "6.1.1" or "N-113004-g0a5813fc68"
+  Version string
+  // build configuration flags, e.g. "--enable-libmp3lame"
+  Configuration []string
+  Encoders map[string]bool
+  Muxers map[string]bool
+  Filters map[string]bool
+}
+
+func (c *Capabilities) HasEncoder(name string) bool {
+  return c.Encoders[name]
+}
+
+func (c *Capabilities) HasMuxer(name string) bool {
+  return c.Muxers[name]
+}
+
+func (c *Capabilities) HasFilter(name string) bool {
+  return c.Filters[name]
+}
+
+// whether ffmpeg was configured with --enable-<lib>
+func (c *Capabilities) Enabled(lib string) bool {
+  for _, f := range c.Configuration {
+    if f == "--enable-" + lib {
+      return true
+    }
+  }
+  return false
+}
+
+// probe ffmpeg version, encoders, muxers & filters. result is cached
+func (f *ffmpeg) Capabilities() (*Capabilities, error) {
+  return f.CapabilitiesContext(context.Background())
+}
+
+// probe ffmpeg capabilities, aborting if ctx is done
+func (f *ffmpeg) CapabilitiesContext(ctx context.Context) (*Capabilities, error) {
+  f.capsMu.Lock()
+  defer f.capsMu.Unlock()
+
+  if f.caps != nil {
+    return f.caps, nil
+  }
+
+  c, err := f.probeCapabilities(ctx)
+  if err != nil {
+    return nil, err
+  }
+  f.caps = c
+  return c, nil
+}
+
+// capabilities for checking arguments, nil if they can not be probed.
+// probing is not retried after failing unless ctx was done
+func (f *ffmpeg) capabilities(ctx context.Context) *Capabilities {
+  f.capsMu.Lock()
+  defer f.capsMu.Unlock()
+
+  if f.caps == nil && !f.capsFailed {
+    c, err := f.probeCapabilities(ctx)
+    if err == nil {
+      f.caps = c
+    } else if ctx.Err() == nil {
+      f.capsFailed = true
+    }
+  }
+  return f.caps
+}
+
+func (f *ffmpeg) probeCapabilities(ctx context.Context) (*Capabilities, error) {
+  out := make([]string, 4)
+  for i, a := range []string{ "-version", "-encoders", "-muxers", "-filters" } {
+    var b bytes.Buffer
+    _, err := f.execute(ctx, nil, &b, []string{ "-hide_banner", a })
+    if err != nil {
+      return nil, err
+    }
+    out[i] = b.String()
+  }
+
+  c := &Capabilities{ Encoders: parseListing(out[1]),
+    Muxers: parseListing(out[2]), Filters: parseFilters(out[3]) }
+  c.Version, c.Configuration = parseVersion(out[0])
+  return c, nil
+}
+
+// version & configuration flags from ffmpeg -version
+func parseVersion(s string) (string, []string) {
+  version, conf := "", []string{}
+
+  for _, l := range strings.Split(s, "\n") {
+    f := strings.Fields(l)
+    switch {
+    case len(f) >= 3 && f[0] == "ffmpeg" && f[1] == "version":
+      version = f[2]
+    case len(f) > 0 && f[0] == "configuration:":
+      conf = f[1:]
+    }
+  }
+  return version, conf
+}
+
+// names from ffmpeg -encoders or -muxers: a legend, a "--" separator, then
+// lines of flags, name(s) & description
+func parseListing(s string) map[string]bool {
+  m := map[string]bool{}
+
+  listing := false
+  for _, l := range strings.Split(s, "\n") {
+    f := strings.Fields(l)
+    if len(f) == 0 {
+      continue
+    }
+    if strings.HasPrefix(f[0], "--") {
+      listing = true
+      continue
+    }
+    if listing && len(f) >= 2 {
+      for _, n := range strings.Split(f[1], ",") {
+        m[n] = true
+      }
+    }
+  }
+  return m
+}
+
+// names from ffmpeg -filters: lines of flags, name, in->out & description
+func parseFilters(s string) map[string]bool {
+  m := map[string]bool{}
+
+  for _, l := range strings.Split(s, "\n") {
+    f := strings.Fields(l)
+    if len(f) >= 3 && strings.Contains(f[2], "->") {
+      m[f[1]] = true
+    }
+  }
+  return m
+}
+
+// encoder, muxer or filter needed by ffmpeg args
+type requirement struct {
+  kind, name string
+}
+
+func requirements(args []string) []requirement {
+  // formats given before the last input are demuxers
+  lastInput := -1
+  for i := range args {
+    if args[i] == "-i" {
+      lastInput = i
+    }
+  }
+
+  r := []requirement{}
+  for i := 0; i < len(args)-1; i++ {
+    a, v := args[i], args[i+1]
+
+    switch {
+    case a == "-c" || strings.HasPrefix(a, "-c:"):
+      if v != "copy" {
+        r = append(r, requirement{ "encoder", v })
+      }
+    case a == "-f":
+      if i > lastInput {
+        r = append(r, requirement{ "muxer", v })
+      }
+    case a == "-af" || a == "-vf" || a == "-filter_complex":
+      for _, n := range filterNames(v) {
+        r = append(r, requirement{ "filter", n })
+      }
+    default:
+      continue
+    }
+    i++
+  }
+  return r
+}
+
+// filter names in a filtergraph, skipping link labels, options & escapes
+func filterNames(graph string) []string {
+  names := []string{}
+
+  var seg strings.Builder
+  flush := func() {
+    s := strings.TrimSpace(seg.String())
+    seg.Reset()
+
+    for strings.HasPrefix(s, "[") {
+      i := strings.Index(s, "]")
+      if i < 0 {
+        return
+      }
+      s = strings.TrimSpace(s[i+1:])
+    }
+    if i := strings.IndexAny(s, "=@["); i >= 0 {
+      s = s[:i]
+    }
+    if len(s) > 0 {
+      names = append(names, s)
+    }
+  }
+
+  quoted := false
+  for i := 0; i < len(graph); i++ {
+    switch c := graph[i]; {
+    case c == '\\':
+      i++
+    case c == '\'':
+      quoted = !quoted
+    case (c == ',' || c == ';') && !quoted:
+      flush()
+    default:
+      seg.WriteByte(c)
+    }
+  }
+  flush()
+
+  return names
+}
+
+// fail with *MissingError if the ffmpeg build lacks anything args need.
+// args are not checked when capabilities can not be probed
+func (f *ffmpeg) require(ctx context.Context, args []string) error {
+  c := f.capabilities(ctx)
+  if c == nil {
+    return nil
+  }
+
+  for _, r := range requirements(args) {
+    var ok bool
+    switch r.kind {
+    case "encoder":
+      ok = c.HasEncoder(r.name)
+    case "muxer":
+      ok = c.HasMuxer(r.name)
+    case "filter":
+      ok = c.HasFilter(r.name)
+    }
+    if !ok {
+      return &MissingError{ Kind: r.kind, Name: r.name, Version: c.Version }
+    }
+  }
+  return nil
+}
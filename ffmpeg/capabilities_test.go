@@ -0,0 +1,93 @@
+package ffmpeg
+
+import (
+  "errors"
+  "context"
+  "reflect"
+  "testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+  v, conf := parseVersion("ffmpeg version 6.1.1 Copyright (c) 2000-2023\n" +
+    "built with gcc 13.2.0\n" +
+    "configuration: --enable-gpl --enable-libmp3lame\n" +
+    "libavutil      58. 29.100 / 58. 29.100\n")
+  if v != "6.1.1" {
+    t.Errorf("Expected 6.1.1, got %v", v)
+  }
+  if !reflect.DeepEqual(conf, []string{ "--enable-gpl", "--enable-libmp3lame" }) {
+    t.Errorf("Expected configuration flags, got %v", conf)
+  }
+
+  enc := parseListing("Encoders:\n" +
+    " V..... = Video\n A..... = Audio\n ------\n" +
+    " V....D png                  PNG (Portable Network Graphics) image\n" +
+    " A....D libmp3lame           libmp3lame MP3 (MPEG audio layer 3)\n")
+  if !reflect.DeepEqual(enc, map[string]bool{ "png": true, "libmp3lame": true }) {
+    t.Errorf("Expected png & libmp3lame, got %v", enc)
+  }
+
+  mux := parseListing("File formats:\n D. = Demuxing supported\n" +
+    " .E = Muxing supported\n --\n  E ipod            iPod H.264 MP4\n" +
+    "  E matroska,webm   Matroska\n")
+  if !reflect.DeepEqual(mux, map[string]bool{ "ipod": true, "matroska": true,
+    "webm": true }) {
+    t.Errorf("Expected ipod, matroska & webm, got %v", mux)
+  }
+
+  fil := parseFilters("Filters:\n  T.. = Timeline support\n" +
+    "  | = Source or sink filter\n" +
+    " ... ebur128           A->N       EBU R128 scanner.\n" +
+    " T.C loudnorm          A->A       EBU R128 loudness normalization\n")
+  if !reflect.DeepEqual(fil, map[string]bool{ "ebur128": true,
+    "loudnorm": true }) {
+    t.Errorf("Expected ebur128 & loudnorm, got %v", fil)
+  }
+}
+
+func TestFilterNames(t *testing.T) {
+  tests := []struct {
+    graph string
+    names []string
+  }{
+    { "loudnorm=I=-23:print_format=json", []string{ "loudnorm" } },
+    { "crop=min(iw\\,ih):min(iw\\,ih),scale=500:500",
+      []string{ "crop", "scale" } },
+    { "[0:a][1:a]concat=n=2:v=0:a=1[a]", []string{ "concat" } },
+    { "[0:a]showwavespic=s=1800x300; [x] volume@v='1,2'",
+      []string{ "showwavespic", "volume" } },
+  }
+
+  for i := range tests {
+    n := filterNames(tests[i].graph)
+    if !reflect.DeepEqual(n, tests[i].names) {
+      t.Errorf("Expected %v, got %v", tests[i].names, n)
+    }
+  }
+}
+
+func TestRequire(t *testing.T) {
+  f := &ffmpeg{ Bin: "false", caps: &Capabilities{ Version: "6.1.1",
+    Encoders: map[string]bool{ "flac": true },
+    Muxers: map[string]bool{ "flac": true, "null": true },
+    Filters: map[string]bool{ "loudnorm": true } } }
+
+  // missing encoder fails before the measuring pass runs
+  _, err := f.Transcode(&TranscodeConfig{ Input: "in.wav", Output: "out.mp3",
+    Preset: "mp3-v0", Normalize: &DefaultLoudness })
+
+  var m *MissingError
+  if !errors.As(err, &m) {
+    t.Fatalf("Expected *MissingError, got %v", err)
+  }
+  if m.Kind != "encoder" || m.Name != "libmp3lame" {
+    t.Errorf("Expected missing encoder libmp3lame, got %v %v", m.Kind, m.Name)
+  }
+
+  // demuxer given before input is not required
+  err = f.require(context.Background(), []string{ "-f", "concat", "-i", "list.txt", "-c:a",
+    "copy", "-af", "loudnorm", "-f", "flac", "out.flac" })
+  if err != nil {
+    t.Errorf("Expected nil, got %v", err)
+  }
+}
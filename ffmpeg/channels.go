@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// channel operation applied during conversion
+type ChannelMode string
+
+const (
+  // average of left & right as mono
+  ChannelsMono ChannelMode = "mono"
+  // left channel only, as mono
+  ChannelsLeft ChannelMode = "left"
+  // right channel only, as mono
+  ChannelsRight ChannelMode = "right"
+  // exchange left & right
+  ChannelsSwap ChannelMode = "swap"
+  // multichannel to stereo
+  ChannelsDownmix ChannelMode = "downmix"
+)
+
+type ChannelConfig struct {
+  Mode ChannelMode
+  // downmix gain of each input channel into left & right, by ffmpeg channel
+  // name (FL, FC, LFE, SL ...) or index (c0, c1 ...). both unset uses
+  // ITU-R BS.775: center & surrounds at -3 dB, LFE dropped
+  Left, Right map[string]float64
+}
+
+// channel names of multichannel layouts by ffprobe ChannelLayout
+var layoutChannels = map[string][]string{
+  "2.1": { "FL", "FR", "LFE" },
+  "3.0": { "FL", "FR", "FC" },
+  "quad": { "FL", "FR", "BL", "BR" },
+  "quad(side)": { "FL", "FR", "SL", "SR" },
+  "4.0": { "FL", "FR", "FC", "BC" },
+  "5.0": { "FL", "FR", "FC", "BL", "BR" },
+  "5.0(side)": { "FL", "FR", "FC", "SL", "SR" },
+  "5.1": { "FL", "FR", "FC", "LFE", "BL", "BR" },
+  "5.1(side)": { "FL", "FR", "FC", "LFE", "SL", "SR" },
+  "6.1": { "FL", "FR", "FC", "LFE", "BC", "SL", "SR" },
+  "7.1": { "FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR" },
+}
+
+// ITU-R BS.775 downmix gains into left & right
+var downmixGains = map[string][2]float64{
+  "FL": { 1, 0 },
+  "FR": { 0, 1 },
+  "FC": { 0.707, 0.707 },
+  "BL": { 0.707, 0 },
+  "BR": { 0, 0.707 },
+  "SL": { 0.707, 0 },
+  "SR": { 0, 0.707 },
+  "BC": { 0.5, 0.5 },
+}
+
+// pan filter for a source with channels & layout as reported by ffprobe
+func (c *ChannelConfig) filter(channels int, layout string) (string, error) {
+  switch c.Mode {
+  case ChannelsMono, ChannelsLeft, ChannelsRight, ChannelsSwap:
+    if channels != 2 {
+      return "", fmt.Errorf("%s requires stereo input, not %d channels",
+        c.Mode, channels)
+    }
+  case ChannelsDownmix:
+    if channels <= 2 {
+      return "", fmt.Errorf("downmix requires more than 2 channels, not %d",
+        channels)
+    }
+  default:
+    return "", fmt.Errorf("unknown channel mode %q", c.Mode)
+  }
+
+  switch c.Mode {
+  case ChannelsMono:
+    return "pan=mono|c0=0.5*c0+0.5*c1", nil
+  case ChannelsLeft:
+    return "pan=mono|c0=c0", nil
+  case ChannelsRight:
+    return "pan=mono|c0=c1", nil
+  case ChannelsSwap:
+    return "pan=stereo|c0=c1|c1=c0", nil
+  }
+
+  names := layoutChannels[layout]
+  if len(names) != channels {
+    names = nil
+  }
+
+  left, right := c.Left, c.Right
+  if left == nil && right == nil {
+    if names == nil {
+      return "", fmt.Errorf("no default downmix for %d channel layout %q",
+        channels, layout)
+    }
+
+    left, right = map[string]float64{}, map[string]float64{}
+    for _, n := range names {
+      left[n], right[n] = downmixGains[n][0], downmixGains[n][1]
+    }
+  }
+
+  l, err := panGains(left, names, channels)
+  if err != nil {
+    return "", err
+  }
+  r, err := panGains(right, names, channels)
+  if err != nil {
+    return "", err
+  }
+  return "pan=stereo|c0=" + l + "|c1=" + r, nil
+}
+
+// sum of gain*channel terms, checking channels exist in the source
+func panGains(gains map[string]float64, names []string,
+  channels int) (string, error) {
+
+  keys := make([]string, 0, len(gains))
+  for k := range gains {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+
+  terms := []string{}
+  for _, k := range keys {
+    if !hasChannel(k, names, channels) {
+      return "", fmt.Errorf("no channel %q in %d channel source", k, channels)
+    }
+    if gains[k] != 0 {
+      terms = append(terms,
+        strconv.FormatFloat(gains[k], 'f', -1, 64) + "*" + k)
+    }
+  }
+
+  if len(terms) == 0 {
+    return "", fmt.Errorf("downmix output channel has no inputs")
+  }
+  return strings.Join(terms, "+"), nil
+}
+
+// whether name or index c<n> is a source channel
+func hasChannel(name string, names []string, channels int) bool {
+  if strings.HasPrefix(name, "c") {
+    n, err := strconv.Atoi(name[1:])
+    return err == nil && n >= 0 && n < channels
+  }
+
+  for _, n := range names {
+    if n == name {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+  "testing"
+)
+
+func TestChannelFilter(t *testing.T) {
+  tests := []struct {
+    c ChannelConfig
+    channels int
+    layout string
+    filter string
+    err bool
+  }{
+    { ChannelConfig{ Mode: ChannelsMono }, 2, "stereo",
+      "pan=mono|c0=0.5*c0+0.5*c1", false },
+    { ChannelConfig{ Mode: ChannelsRight }, 2, "stereo", "pan=mono|c0=c1", false },
+    { ChannelConfig{ Mode: ChannelsSwap }, 2, "stereo",
+      "pan=stereo|c0=c1|c1=c0", false },
+    { ChannelConfig{ Mode: ChannelsLeft }, 1, "mono", "", true },
+    { ChannelConfig{ Mode: ChannelsDownmix }, 6, "5.1(side)",
+      "pan=stereo|c0=0.707*FC+1*FL+0.707*SL|c1=0.707*FC+1*FR+0.707*SR", false },
+    { ChannelConfig{ Mode: ChannelsDownmix }, 2, "stereo", "", true },
+    { ChannelConfig{ Mode: ChannelsDownmix }, 6, "", "", true },
+    { ChannelConfig{ Mode: ChannelsDownmix,
+      Left: map[string]float64{ "c0": 1, "c2": 0.5 },
+      Right: map[string]float64{ "c1": 1, "c2": 0.5 } }, 3, "",
+      "pan=stereo|c0=1*c0+0.5*c2|c1=1*c1+0.5*c2", false },
+    { ChannelConfig{ Mode: ChannelsDownmix,
+      Left: map[string]float64{ "BL": 1 }, Right: map[string]float64{} },
+      6, "5.1(side)", "", true },
+    { ChannelConfig{ Mode: "surround" }, 2, "stereo", "", true },
+  }
+
+  for i := range tests {
+    f, err := tests[i].c.filter(tests[i].channels, tests[i].layout)
+    if (err != nil) != tests[i].err {
+      t.Errorf("Expected error %v, got %v", tests[i].err, err)
+    }
+    if f != tests[i].filter {
+      t.Errorf("Expected %v, got %v", tests[i].filter, f)
+    }
+  }
+}
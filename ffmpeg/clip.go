@@ -0,0 +1,224 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "time"
+  "errors"
+  "context"
+)
+
+// shape of a fade, as named by the ffmpeg afade filter
+type FadeCurve string
+
+const (
+  FadeLinear FadeCurve = "tri"
+  FadeQuarterSine FadeCurve = "qsin"
+  FadeHalfSine FadeCurve = "hsin"
+  FadeExponential FadeCurve = "exp"
+  FadeLogarithmic FadeCurve = "log"
+)
+
+type Fade struct {
+  Duration time.Duration
+  // default linear
+  Curve FadeCurve
+}
+
+type ClipConfig struct {
+  FadeIn, FadeOut Fade
+  // codec, quality & tags of the clip. Input, Output & Duration are set
+  // from the clip; codec is taken from the output extension when neither
+  // Preset nor Codec is set. TrimSilence is not supported
+  Transcode TranscodeConfig
+}
+
+type CrossfadeConfig struct {
+  // overlap of the end of the first input & start of the second
+  Duration time.Duration
+  // fade out of the first input & in of the second, default linear
+  CurveOut, CurveIn FadeCurve
+  // codec of the output, from its extension when neither is set
+  Preset string
+  Codec Codec
+  Quality Quality
+  // tags for the output, tags of the first input are carried over when nil
+  Meta *Metadata
+}
+
+// cut duration of input from start into output. c may be nil
+func (f *ffmpeg) ExtractClip(input string, start, duration time.Duration,
+  output string, c *ClipConfig) (string, error) {
+
+  return f.ExtractClipContext(context.Background(), input, start, duration,
+    output, c)
+}
+
+// cut clip from input, aborting if ctx is done
+func (f *ffmpeg) ExtractClipContext(ctx context.Context, input string,
+  start, duration time.Duration, output string,
+  c *ClipConfig) (string, error) {
+
+  if c == nil {
+    c = &ClipConfig{}
+  }
+
+  if start < 0 || duration <= 0 {
+    return "", errors.New("clip start must not be negative & duration " +
+      "must be positive")
+  }
+  if c.Transcode.TrimSilence != nil {
+    return "", errors.New("TrimSilence not supported for clips")
+  }
+
+  // clip ends early when input is shorter
+  if total := seconds(probeDuration(ctx, input)); total > 0 {
+    if start >= total {
+      return "", fmt.Errorf("clip start %v is beyond end of %v", start, input)
+    }
+    if start + duration > total {
+      duration = total - start
+    }
+  }
+
+  filters, err := c.filters(duration)
+  if err != nil {
+    return "", err
+  }
+
+  t := c.Transcode
+  t.Input, t.Output = input, output
+  t.start, t.length, t.Duration = start, duration, duration.Seconds()
+  if len(t.Preset) == 0 && len(t.Codec) == 0 {
+    t.Codec, _ = codecFor(output)
+  }
+
+  // seeking to a sample requires decoding
+  _, q, err := t.codec()
+  if err != nil {
+    return "", err
+  }
+  if q.Copy {
+    return "", errors.New("clips require re-encoding, not copy")
+  }
+
+  s, _, err := f.transcode(ctx, &t, filters...)
+  return s, err
+}
+
+// afade filters for a clip of duration
+func (c *ClipConfig) filters(duration time.Duration) ([]string, error) {
+  if c.FadeIn.Duration < 0 || c.FadeOut.Duration < 0 {
+    return nil, errors.New("fade duration must not be negative")
+  }
+  if c.FadeIn.Duration + c.FadeOut.Duration > duration {
+    return nil, fmt.Errorf("fades are longer than the %v clip", duration)
+  }
+
+  filters := []string{}
+  if c.FadeIn.Duration > 0 {
+    filters = append(filters, fmt.Sprintf("afade=t=in:d=%v:curve=%v",
+      c.FadeIn.Duration.Seconds(), c.FadeIn.curve()))
+  }
+  if c.FadeOut.Duration > 0 {
+    filters = append(filters, fmt.Sprintf("afade=t=out:st=%v:d=%v:curve=%v",
+      (duration - c.FadeOut.Duration).Seconds(),
+      c.FadeOut.Duration.Seconds(), c.FadeOut.curve()))
+  }
+  return filters, nil
+}
+
+func (f Fade) curve() FadeCurve {
+  return curveOrLinear(f.Curve)
+}
+
+func curveOrLinear(c FadeCurve) FadeCurve {
+  if len(c) == 0 {
+    return FadeLinear
+  }
+  return c
+}
+
+// join first & second into output, overlapping them by c.Duration
+func (f *ffmpeg) Crossfade(first, second, output string,
+  c *CrossfadeConfig) (string, error) {
+
+  return f.CrossfadeContext(context.Background(), first, second, output, c)
+}
+
+// crossfade join, aborting if ctx is done
+func (f *ffmpeg) CrossfadeContext(ctx context.Context, first, second,
+  output string, c *CrossfadeConfig) (string, error) {
+
+  if c == nil {
+    return "", errors.New("crossfade config with a duration is required")
+  }
+  if c.Duration <= 0 {
+    return "", errors.New("crossfade duration must be positive")
+  }
+  for _, in := range []string{ first, second } {
+    if d := seconds(probeDuration(ctx, in)); d > 0 && c.Duration > d {
+      return "", fmt.Errorf("crossfade %v is longer than %v", c.Duration, in)
+    }
+  }
+
+  // output only appears once fully written
+  var s string
+  err := f.writeAtomic(output, func(tmp string) error {
+    a, err := crossfadeArgs(first, second, output, tmp, c)
+    if err != nil {
+      return err
+    }
+
+    s, err = f.ExecContext(ctx, a...)
+    return err
+  })
+  return s, err
+}
+
+// build ffmpeg arguments writing to tmp, with codec & tags for output
+func crossfadeArgs(first, second, output, tmp string,
+  c *CrossfadeConfig) ([]string, error) {
+
+  t := &TranscodeConfig{ Preset: c.Preset, Codec: c.Codec, Quality: c.Quality }
+  if len(t.Preset) == 0 && len(t.Codec) == 0 {
+    t.Codec, _ = codecFor(output)
+  }
+  codec, q, err := t.codec()
+  if err != nil {
+    return []string{}, err
+  }
+  if q.Copy {
+    return []string{}, errors.New("crossfade requires re-encoding, not copy")
+  }
+  info := codecs[codec]
+  if c.Meta != nil {
+    if err := c.Meta.supported(info.tags); err != nil {
+      return []string{}, err
+    }
+  }
+
+  a := []string{ "-i", first, "-i", second }
+
+  artwork := info.artwork && c.Meta != nil && len(c.Meta.Artwork) > 0
+  if artwork {
+    a = append(a, "-i", c.Meta.Artwork)
+  }
+
+  a = append(a, "-filter_complex",
+    fmt.Sprintf("[0:a][1:a]acrossfade=d=%v:c1=%v:c2=%v[a]",
+    c.Duration.Seconds(), curveOrLinear(c.CurveOut), curveOrLinear(c.CurveIn)),
+    "-map", "[a]")
+  a = append(a, codecArgs(codec, q)...)
+
+  // tags carried over from first input
+  if c.Meta == nil {
+    a = append(a, "-map_metadata", "0")
+  } else {
+    a = append(a, metadataArgs(info.tags, *c.Meta)...)
+    if artwork {
+      a = append(a, artworkArgs(info.tags, 2)...)
+    }
+  }
+
+  return append(a, "-f", info.format, "-y", tmp), nil
+}
@@ -0,0 +1,83 @@
+package ffmpeg
+
+import (
+  "time"
+  "strings"
+  "testing"
+)
+
+func TestExtractClip(t *testing.T) {
+  defer fakeFfprobe(t, 44100)()
+
+  d := NewDryRun()
+  d.Bin = "ffmpeg"
+
+  c := &ClipConfig{ FadeIn: Fade{ Duration: time.Second },
+    FadeOut: Fade{ Duration: 2 * time.Second, Curve: FadeQuarterSine },
+    Transcode: TranscodeConfig{ Meta: Metadata{ Title: "Promo" },
+      Normalize: &DefaultLoudness } }
+
+  _, err := d.ExtractClip("in.flac", 90 * time.Second, 30 * time.Second,
+    "clip.mp3", c)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  cmds := d.Commands()
+  if len(cmds) != 3 {
+    t.Fatalf("Expected 3 commands, got %d", len(cmds))
+  }
+
+  // loudness measured over the faded clip
+  fades := "afade=t=in:d=1:curve=tri,afade=t=out:st=28:d=2:curve=qsin"
+  for i := range cmds[:2] {
+    a := strings.Join(cmds[i], " ")
+    if !strings.Contains(a, "-ss 90 -t 30 -i in.flac") {
+      t.Errorf("Expected accurate input seek, got %v", a)
+    }
+    if !strings.Contains(a, fades) {
+      t.Errorf("Expected %v, got %v", fades, a)
+    }
+  }
+  if !strings.Contains(strings.Join(cmds[1], " "), "-metadata title=Promo") {
+    t.Errorf("Expected title tag, got %v", cmds[1])
+  }
+
+  _, err = d.ExtractClip("in.flac", 0, time.Second, "clip.mp3", c)
+  if err == nil {
+    t.Errorf("Expected error for fades longer than clip")
+  }
+
+  c = &ClipConfig{ Transcode: TranscodeConfig{ Quality: Quality{ Copy: true } } }
+  _, err = d.ExtractClip("in.flac", 0, time.Second, "clip.mp3", c)
+  if err == nil {
+    t.Errorf("Expected error for copy")
+  }
+}
+
+func TestCrossfadeArgs(t *testing.T) {
+  a, err := crossfadeArgs("a.flac", "b.flac", "out.flac", "tmp.flac",
+    &CrossfadeConfig{ Duration: 1500 * time.Millisecond,
+    CurveIn: FadeExponential })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  e := "-i a.flac -i b.flac -filter_complex " +
+    "[0:a][1:a]acrossfade=d=1.5:c1=tri:c2=exp[a] -map [a] -c:a flac " +
+    "-map_metadata 0 -f flac -y tmp.flac"
+  if strings.Join(a, " ") != e {
+    t.Errorf("Expected %v, got %v", e, strings.Join(a, " "))
+  }
+}
+
+func TestCrossfadeConfig(t *testing.T) {
+  d := NewDryRun()
+
+  // a duration is required
+  for _, c := range []*CrossfadeConfig{ nil, &CrossfadeConfig{} } {
+    if _, err := d.Crossfade("a.flac", "b.flac", "out.flac", c); err == nil {
+      t.Errorf("Expected error for %v", c)
+    }
+  }
+}
@@ -0,0 +1,241 @@
+package ffmpeg
+
+import (
+  "os"
+  "fmt"
+  "errors"
+  "context"
+  "strings"
+  "io/ioutil"
+  "path/filepath"
+)
+
+type ConcatConfig struct {
+  // codec used when re-encoding, from the output extension when neither is
+  // set. inputs sharing a codec & sample format are stream copied unless a
+  // different codec is requested. mp3 & aac are always re-encoded unless
+  // Quality.Copy is set, as copying keeps each file's encoder delay &
+  // padding, leaving gaps
+  Preset string
+  Codec Codec
+  Quality Quality
+  // tags for the output, tags of the first input are carried over when nil
+  Meta *Metadata
+}
+
+// audio stream details compared before joining
+type concatInput struct {
+  path, codec, sampleRate, sampleFmt, bits string
+  channels int
+}
+
+// join inputs end to end into output without gaps. c may be nil to stream
+// copy inputs that share a codec
+func (f *ffmpeg) Concat(inputs []string, output string,
+  c *ConcatConfig) (string, error) {
+
+  return f.ConcatContext(context.Background(), inputs, output, c)
+}
+
+// join inputs into output, aborting if ctx is done
+func (f *ffmpeg) ConcatContext(ctx context.Context, inputs []string,
+  output string, c *ConcatConfig) (string, error) {
+
+  if c == nil {
+    c = &ConcatConfig{}
+  }
+
+  if len(inputs) < 2 {
+    return "", errors.New("concat requires at least 2 inputs")
+  }
+
+  probed := make([]*concatInput, len(inputs))
+  for i := range inputs {
+    in, err := probeConcatInput(ctx, inputs[i])
+    if err != nil {
+      return "", err
+    }
+
+    if i > 0 && (in.sampleRate != probed[0].sampleRate ||
+      in.channels != probed[0].channels) {
+      return "", fmt.Errorf("%v: %v Hz %v channels does not match %v Hz " +
+        "%v channels of %v", in.path, in.sampleRate, in.channels,
+        probed[0].sampleRate, probed[0].channels, probed[0].path)
+    }
+    probed[i] = in
+  }
+
+  // concat demuxer when stream copying, list file is its input
+  var list string
+  streamCopy := c.streamCopy(probed, output)
+  if streamCopy {
+    var err error
+    list, err = concatList(inputs)
+    if err != nil {
+      return "", err
+    }
+    defer os.Remove(list)
+  }
+
+  // output only appears once fully written
+  var s string
+  err := f.writeAtomic(output, func(tmp string) error {
+    a, err := concatArgs(list, inputs, tmp, c, streamCopy)
+    if err != nil {
+      return err
+    }
+
+    s, err = f.ExecContext(ctx, a...)
+    return err
+  })
+  return s, err
+}
+
+// stream copy when all inputs share a codec & sample format that matches
+// the output codec
+func (c *ConcatConfig) streamCopy(in []*concatInput, output string) bool {
+  for i := range in {
+    if in[i].codec != in[0].codec || in[i].sampleFmt != in[0].sampleFmt ||
+      in[i].bits != in[0].bits {
+      return false
+    }
+  }
+
+  codec, q, err := c.codec(output)
+  if err != nil {
+    return false
+  }
+  if q.Copy || len(codec) == 0 {
+    return true
+  }
+
+  // encoder delay & padding are kept per file
+  if in[0].codec == "mp3" || in[0].codec == "aac" {
+    return false
+  }
+  return string(codec) == in[0].codec ||
+    (codec == CodecWav && strings.HasPrefix(in[0].codec, "pcm_"))
+}
+
+// codec & quality from config, or from output extension when unset. codec
+// is empty when neither gives one
+func (c *ConcatConfig) codec(output string) (Codec, Quality, error) {
+  t := &TranscodeConfig{ Preset: c.Preset, Codec: c.Codec, Quality: c.Quality }
+  if len(t.Preset) == 0 && len(t.Codec) == 0 {
+    out, ok := codecFor(output)
+    if !ok {
+      return "", c.Quality, nil
+    }
+    t.Codec = out
+  }
+  return t.codec()
+}
+
+// build ffmpeg arguments. when copying, list is the concat demuxer file
+// list; otherwise inputs are joined with the concat filter
+func concatArgs(list string, inputs []string, output string, c *ConcatConfig,
+  streamCopy bool) ([]string, error) {
+
+  a := []string{}
+  n := 1
+
+  if streamCopy {
+    a = append(a, "-f", "concat", "-safe", "0", "-i", list)
+  } else {
+    for i := range inputs {
+      a = append(a, "-i", inputs[i])
+    }
+    n = len(inputs)
+  }
+
+  out, ok := codecFor(output)
+  if c.Meta != nil && ok {
+    if err := c.Meta.supported(codecs[out].tags); err != nil {
+      return []string{}, err
+    }
+  }
+  artwork := ok && codecs[out].artwork && c.Meta != nil &&
+    len(c.Meta.Artwork) > 0
+
+  // tags carried over from first input
+  if c.Meta == nil {
+    if streamCopy {
+      a = append(a, "-i", inputs[0])
+      a = append(a, "-map_metadata", "1")
+    } else {
+      a = append(a, "-map_metadata", "0")
+    }
+  } else if artwork {
+    a = append(a, "-i", c.Meta.Artwork)
+  }
+
+  if streamCopy {
+    a = append(a, "-map", "0:a", "-c:a", "copy")
+  } else {
+    codec, q, err := c.codec(output)
+    if err != nil {
+      return []string{}, err
+    }
+    if len(codec) == 0 || q.Copy {
+      return []string{}, errors.New("inputs can not be stream copied, " +
+        "a codec to re-encode with is required")
+    }
+
+    pads := ""
+    for i := range inputs {
+      pads += fmt.Sprintf("[%d:a]", i)
+    }
+    a = append(a, "-filter_complex",
+      fmt.Sprintf("%sconcat=n=%d:v=0:a=1[a]", pads, len(inputs)), "-map", "[a]")
+    a = append(a, codecArgs(codec, q)...)
+  }
+
+  if c.Meta != nil && ok {
+    a = append(a, metadataArgs(codecs[out].tags, *c.Meta)...)
+    if artwork {
+      a = append(a, artworkArgs(codecs[out].tags, n)...)
+    }
+  }
+
+  return append(a, "-y", output), nil
+}
+
+// write concat demuxer file list to temp file
+func concatList(inputs []string) (string, error) {
+  tmp, err := ioutil.TempFile("", "concat-*.txt")
+  if err != nil {
+    return "", err
+  }
+  defer tmp.Close()
+
+  for i := range inputs {
+    p, err := filepath.Abs(inputs[i])
+    if err != nil {
+      os.Remove(tmp.Name())
+      return "", err
+    }
+
+    // single quotes escaped as '\''
+    _, err = fmt.Fprintf(tmp, "file '%s'\n",
+      strings.Replace(p, "'", `'\''`, -1))
+    if err != nil {
+      os.Remove(tmp.Name())
+      return "", err
+    }
+  }
+
+  return tmp.Name(), nil
+}
+
+// probe first audio stream of input
+func probeConcatInput(ctx context.Context,
+  input string) (*concatInput, error) {
+
+  s, err := audioStream(ctx, input)
+  if err != nil {
+    return nil, err
+  }
+  return &concatInput{ path: input, codec: s.CodecName,
+    sampleRate: s.SampleRate, sampleFmt: s.SampleFmt,
+    bits: s.BitsPerRawSample, channels: s.Channels }, nil
+}
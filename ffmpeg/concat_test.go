@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestConcatArgs(t *testing.T) {
+  inputs := []string{ "d1.flac", "d2.flac" }
+
+  tests := []struct {
+    list string
+    output string
+    config *ConcatConfig
+    copy bool
+    args string
+  }{
+    { list: "list.txt", output: "out.flac", config: &ConcatConfig{}, copy: true,
+      args: "-f concat -safe 0 -i list.txt -i d1.flac -map_metadata 1 " +
+        "-map 0:a -c:a copy -y out.flac" },
+    { output: "out.mp3", copy: false,
+      config: &ConcatConfig{ Codec: CodecMp3,
+        Meta: &Metadata{ Artist: "A", Title: "T", Artwork: "cover.jpg" } },
+      args: "-i d1.flac -i d2.flac -i cover.jpg -filter_complex " +
+        "[0:a][1:a]concat=n=2:v=0:a=1[a] -map [a] -c:a libmp3lame -qscale:a 0 " +
+        "-id3v2_version 4 -metadata artist=A -metadata album= -metadata disc= " +
+        "-metadata track= -metadata title=T -metadata date= -map 2:v -c:v copy " +
+        "-metadata:s:v title=Album cover -metadata:s:v comment=Cover (Front) " +
+        "-y out.mp3" },
+  }
+
+  for i := range tests {
+    a, err := concatArgs(tests[i].list, inputs, tests[i].output,
+      tests[i].config, tests[i].copy)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if strings.Join(a, " ") != tests[i].args {
+      t.Errorf("Expected %v, got %v", tests[i].args, strings.Join(a, " "))
+    }
+  }
+
+  _, err := concatArgs("", inputs, "out.bin", &ConcatConfig{}, false)
+  if err == nil {
+    t.Errorf("Expected error when re-encoding without codec")
+  }
+}
+
+func TestConcatStreamCopy(t *testing.T) {
+  flac16 := &concatInput{ codec: "flac", sampleFmt: "s16", bits: "16" }
+  flac24 := &concatInput{ codec: "flac", sampleFmt: "s32", bits: "24" }
+  mp3 := &concatInput{ codec: "mp3", sampleFmt: "fltp", bits: "0" }
+
+  tests := []struct {
+    in []*concatInput
+    output string
+    config *ConcatConfig
+    copy bool
+  }{
+    { []*concatInput{ flac16, flac16 }, "out.flac", &ConcatConfig{}, true },
+    { []*concatInput{ flac16, flac16 }, "out.mp3", &ConcatConfig{}, false },
+    { []*concatInput{ flac16, flac24 }, "out.flac", &ConcatConfig{}, false },
+    { []*concatInput{ mp3, mp3 }, "out.mp3", &ConcatConfig{}, false },
+    { []*concatInput{ mp3, mp3 }, "out.mp3",
+      &ConcatConfig{ Quality: Quality{ Copy: true } }, true },
+  }
+
+  for i := range tests {
+    c := tests[i].config.streamCopy(tests[i].in, tests[i].output)
+    if c != tests[i].copy {
+      t.Errorf("Expected copy %v for %v, got %v", tests[i].copy,
+        tests[i].output, c)
+    }
+  }
+}
@@ -0,0 +1,255 @@
+package ffmpeg
+
+import (
+  "io"
+  "math"
+  "time"
+  "errors"
+  "context"
+  "strconv"
+  "encoding/binary"
+)
+
+// pcm sample format produced by a Decoder
+type SampleFormat int
+
+const (
+  SampleFloat32 SampleFormat = iota
+  SampleInt16
+)
+
+// bytes per sample, ffmpeg muxer & encoder
+func (s SampleFormat) pcm() (int, string, string) {
+  if s == SampleInt16 {
+    return 2, "s16le", "pcm_s16le"
+  }
+  return 4, "f32le", "pcm_f32le"
+}
+
+type DecodeConfig struct {
+  Format SampleFormat
+  // one slice per channel instead of interleaved samples
+  Planar bool
+  // samples per channel in each frame, default 4096
+  FrameSize int
+  // position to start decoding from, accurate to the sample
+  Start time.Duration
+}
+
+// block of decoded samples. only the slices for the configured format are
+// set: a single interleaved slice (L R L R ...) or one slice per channel
+type Frame struct {
+  // position of the first sample from the start of input
+  Time time.Duration
+  // samples per channel
+  Samples int
+  Float32 [][]float32
+  Int16 [][]int16
+}
+
+// reads pcm frames from the first audio stream of an input, at its native
+// sample rate & channels
+type Decoder struct {
+  SampleRate int
+  Channels int
+  // as reported by ffprobe, e.g. "stereo" or "5.1(side)"
+  ChannelLayout string
+
+  c DecodeConfig
+  open func(start time.Duration) (io.ReadCloser, error)
+  r io.ReadCloser
+  // samples per channel from start of input
+  pos int64
+  buf []byte
+}
+
+// decode input to pcm frames read with Next. Close must be called
+func (f *ffmpeg) Decode(input string, c *DecodeConfig) (*Decoder, error) {
+  return f.DecodeContext(context.Background(), input, c)
+}
+
+// decode input to pcm frames, aborting if ctx is done. ctx applies to the
+// decoder until it is closed
+func (f *ffmpeg) DecodeContext(ctx context.Context, input string,
+  c *DecodeConfig) (*Decoder, error) {
+
+  if c == nil {
+    c = &DecodeConfig{}
+  }
+
+  s, err := audioStream(ctx, input)
+  if err != nil {
+    return nil, err
+  }
+  rate, err := strconv.Atoi(s.SampleRate)
+  if err != nil || rate <= 0 || s.Channels <= 0 {
+    return nil, errors.New("unable to determine sample rate & channels of " +
+      input)
+  }
+
+  cfg := *c
+  open := func(start time.Duration) (io.ReadCloser, error) {
+    return f.pcmReader(ctx, decodeArgs(input, &cfg, start)), nil
+  }
+  return newDecoder(rate, s.Channels, s.ChannelLayout, c, open)
+}
+
+func newDecoder(rate, channels int, layout string, c *DecodeConfig,
+  open func(start time.Duration) (io.ReadCloser, error)) (*Decoder, error) {
+
+  if c.FrameSize < 0 {
+    return nil, errors.New("frame size must not be negative")
+  }
+
+  d := &Decoder{ SampleRate: rate, Channels: channels, ChannelLayout: layout,
+    c: *c, open: open }
+  if d.c.FrameSize == 0 {
+    d.c.FrameSize = 4096
+  }
+
+  return d, d.Seek(c.Start)
+}
+
+// ffmpeg arguments writing raw pcm of input from start to stdout
+func decodeArgs(input string, c *DecodeConfig, start time.Duration) []string {
+  _, format, codec := c.Format.pcm()
+
+  a := []string{ "-hide_banner", "-nostats" }
+  if start > 0 {
+    a = append(a, "-ss", strconv.FormatFloat(start.Seconds(), 'f', -1, 64))
+  }
+  return append(a, "-i", input, "-map", "0:a:0", "-c:a", codec,
+    "-f", format, "-")
+}
+
+// stdout of ffmpeg run in the background. read errors are those of the run
+type pcmPipe struct {
+  *io.PipeReader
+  cancel context.CancelFunc
+  done chan struct{}
+}
+
+func (f *ffmpeg) pcmReader(ctx context.Context, args []string) io.ReadCloser {
+  ctx, cancel := context.WithCancel(ctx)
+  r, w := io.Pipe()
+
+  p := &pcmPipe{ PipeReader: r, cancel: cancel, done: make(chan struct{}) }
+  go func() {
+    _, err := f.run(ctx, nil, w, args)
+    w.CloseWithError(err)
+    close(p.done)
+  }()
+  return p
+}
+
+// stop ffmpeg & wait for it to exit
+func (p *pcmPipe) Close() error {
+  p.cancel()
+  p.PipeReader.Close()
+  <-p.done
+  return nil
+}
+
+// next frame, the last may be short. io.EOF once input is fully decoded
+func (d *Decoder) Next() (*Frame, error) {
+  if d.r == nil {
+    return nil, errors.New("decoder is closed")
+  }
+
+  size, _, _ := d.c.Format.pcm()
+  sample := size * d.Channels
+  if len(d.buf) != d.c.FrameSize * sample {
+    d.buf = make([]byte, d.c.FrameSize * sample)
+  }
+
+  n, err := io.ReadFull(d.r, d.buf)
+  if err != nil && err != io.ErrUnexpectedEOF {
+    return nil, err
+  }
+
+  samples := n / sample
+  if samples == 0 {
+    return nil, io.EOF
+  }
+
+  fr := d.frame(d.buf[:samples*sample], samples)
+  d.pos += int64(samples)
+  return fr, nil
+}
+
+// continue decoding from offset
+func (d *Decoder) Seek(offset time.Duration) error {
+  if offset < 0 {
+    return errors.New("seek offset must not be negative")
+  }
+  d.Close()
+
+  r, err := d.open(offset)
+  if err != nil {
+    return err
+  }
+  d.r = r
+  d.pos = samplesAt(offset, d.SampleRate)
+  return nil
+}
+
+// position of the next sample to be read
+func (d *Decoder) Time() time.Duration {
+  return time.Duration(d.pos * int64(time.Second) / int64(d.SampleRate))
+}
+
+// nearest sample to offset
+func samplesAt(offset time.Duration, rate int) int64 {
+  return (int64(offset) * int64(rate) + int64(time.Second) / 2) /
+    int64(time.Second)
+}
+
+// stop decoding
+func (d *Decoder) Close() error {
+  if d.r == nil {
+    return nil
+  }
+  err := d.r.Close()
+  d.r = nil
+  return err
+}
+
+// convert whole samples in b to a frame
+func (d *Decoder) frame(b []byte, samples int) *Frame {
+  fr := &Frame{ Time: d.Time(), Samples: samples }
+
+  ch, planes := d.Channels, 1
+  if d.c.Planar {
+    planes = ch
+  }
+  // index of sample i in its plane
+  at := func(i int) (int, int) {
+    if planes == 1 {
+      return 0, i
+    }
+    return i % ch, i / ch
+  }
+
+  total := samples * ch
+  if d.c.Format == SampleInt16 {
+    fr.Int16 = make([][]int16, planes)
+    for p := range fr.Int16 {
+      fr.Int16[p] = make([]int16, total / planes)
+    }
+    for i := 0; i < total; i++ {
+      p, j := at(i)
+      fr.Int16[p][j] = int16(binary.LittleEndian.Uint16(b[i*2:]))
+    }
+    return fr
+  }
+
+  fr.Float32 = make([][]float32, planes)
+  for p := range fr.Float32 {
+    fr.Float32[p] = make([]float32, total / planes)
+  }
+  for i := 0; i < total; i++ {
+    p, j := at(i)
+    fr.Float32[p][j] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+  }
+  return fr
+}
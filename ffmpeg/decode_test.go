@@ -0,0 +1,124 @@
+package ffmpeg
+
+import (
+  "io"
+  "os"
+  "time"
+  "errors"
+  "context"
+  "strings"
+  "testing"
+  "reflect"
+  "io/ioutil"
+  "encoding/binary"
+)
+
+func TestDecodeArgs(t *testing.T) {
+  a := decodeArgs("in.flac", &DecodeConfig{ Format: SampleInt16 },
+    1500 * time.Millisecond)
+  e := "-hide_banner -nostats -ss 1.5 -i in.flac -map 0:a:0 " +
+    "-c:a pcm_s16le -f s16le -"
+  if strings.Join(a, " ") != e {
+    t.Errorf("Expected %v, got %v", e, strings.Join(a, " "))
+  }
+}
+
+func TestDecoder(t *testing.T) {
+  // 6 stereo int16 samples: L = i, R = -i
+  b := make([]byte, 24)
+  for i := 0; i < 6; i++ {
+    binary.LittleEndian.PutUint16(b[i*4:], uint16(i))
+    binary.LittleEndian.PutUint16(b[i*4+2:], uint16(int16(-i)))
+  }
+
+  tmp, err := ioutil.TempFile("", "decode")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.Remove(tmp.Name())
+  tmp.Write(b)
+  tmp.Close()
+
+  m := &MockFfmpeg{}
+  d, err := m.Decode(tmp.Name(), &DecodeConfig{ Format: SampleInt16,
+    FrameSize: 4 })
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer d.Close()
+
+  fr, err := d.Next()
+  if err != nil {
+    t.Fatal(err)
+  }
+  e := [][]int16{ { 0, 0, 1, -1, 2, -2, 3, -3 } }
+  if fr.Samples != 4 || !reflect.DeepEqual(fr.Int16, e) {
+    t.Errorf("Expected %v, got %v", e, fr.Int16)
+  }
+
+  // last frame is short
+  fr, err = d.Next()
+  if err != nil || fr.Samples != 2 {
+    t.Fatalf("Expected 2 samples, got %v %v", fr, err)
+  }
+  if _, err = d.Next(); err != io.EOF {
+    t.Errorf("Expected io.EOF, got %v", err)
+  }
+
+  // planar from 2nd sample
+  d, err = m.Decode(tmp.Name(), &DecodeConfig{ Format: SampleInt16,
+    Planar: true, Start: time.Second / 44100 })
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer d.Close()
+
+  fr, err = d.Next()
+  if err != nil {
+    t.Fatal(err)
+  }
+  e = [][]int16{ { 1, 2, 3, 4, 5 }, { -1, -2, -3, -4, -5 } }
+  if !reflect.DeepEqual(fr.Int16, e) {
+    t.Errorf("Expected %v, got %v", e, fr.Int16)
+  }
+  if fr.Time != 22675 * time.Nanosecond {
+    t.Errorf("Expected frame at 22.675µs, got %v", fr.Time)
+  }
+}
+
+func TestDecoderExecError(t *testing.T) {
+  f := &ffmpeg{ Bin: "false" }
+  open := func(start time.Duration) (io.ReadCloser, error) {
+    return f.pcmReader(context.Background(), decodeArgs("in.flac",
+      &DecodeConfig{}, start)), nil
+  }
+
+  d, err := newDecoder(44100, 2, "stereo", &DecodeConfig{}, open)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer d.Close()
+
+  _, err = d.Next()
+  var e *ExecError
+  if !errors.As(err, &e) {
+    t.Errorf("Expected *ExecError, got %v", err)
+  }
+}
+
+func TestDecodeNilConfig(t *testing.T) {
+  defer fakeFfprobe(t, 48000)()
+
+  // nil config decodes float32 in 4096 sample frames
+  f := &ffmpeg{ Bin: "false" }
+  d, err := f.Decode("in.flac", nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer d.Close()
+
+  if d.SampleRate != 48000 || d.c.Format != SampleFloat32 ||
+    d.c.FrameSize != 4096 {
+    t.Errorf("Expected defaults at 48000 Hz, got %v %+v", d.SampleRate, d.c)
+  }
+}
@@ -0,0 +1,88 @@
+package ffmpeg
+
+import (
+  "os"
+  "fmt"
+  "sync"
+  "os/exec"
+  "path/filepath"
+)
+
+// ffmpeg command lines recorded instead of run
+type commandLog struct {
+  sync.Mutex
+  commands [][]string
+  // temp files named so far
+  temps int
+}
+
+// ffmpeg wrapper which records the ffmpeg commands each operation would
+// run, in order, without running them or writing output. output is written
+// to a temp file then renamed, recorded as "mv tmp output" after the command
+// writing it. ffprobe is still used to inspect inputs. measurements
+// (loudness, replaygain, silence) are zero, and OptimizeArtwork records only
+// its first attempt as retries for MaxBytes depend on the size written
+type DryRun struct {
+  *ffmpeg
+}
+
+// new dry run, commands use the ffmpeg found on system or "ffmpeg"
+func NewDryRun() *DryRun {
+  bin, err := exec.LookPath("ffmpeg")
+  if err != nil {
+    bin = "ffmpeg"
+  }
+  return &DryRun{ &ffmpeg{ Bin: bin, dryRun: &commandLog{} } }
+}
+
+// recorded commands in order, each starting with the ffmpeg binary or "mv"
+func (d *DryRun) Commands() [][]string {
+  d.dryRun.Lock()
+  defer d.dryRun.Unlock()
+
+  c := make([][]string, len(d.dryRun.commands))
+  copy(c, d.dryRun.commands)
+  return c
+}
+
+// forget recorded commands
+func (d *DryRun) Reset() {
+  d.dryRun.Lock()
+  d.dryRun.commands = nil
+  d.dryRun.temps = 0
+  d.dryRun.Unlock()
+}
+
+func (l *commandLog) record(bin string, args []string) {
+  l.Lock()
+  l.commands = append(l.commands, append([]string{ bin }, args...))
+  l.Unlock()
+}
+
+// temp path next to path; a dry run only names it, numbered so each is
+// distinct
+func (f *ffmpeg) tempFileFor(path string) (string, error) {
+  if f.dryRun == nil {
+    return tempFileFor(path)
+  }
+
+  f.dryRun.Lock()
+  f.dryRun.temps++
+  n := f.dryRun.temps
+  f.dryRun.Unlock()
+
+  dir, file := filepath.Split(path)
+  ext := filepath.Ext(file)
+  return filepath.Join(dir, fmt.Sprintf(".%s-tmp%d%s",
+    file[:len(file)-len(ext)], n, ext)), nil
+}
+
+// rename tmp over path; a dry run records it
+func (f *ffmpeg) rename(tmp, path string) error {
+  if f.dryRun == nil {
+    return os.Rename(tmp, path)
+  }
+
+  f.dryRun.record("mv", []string{ tmp, path })
+  return nil
+}
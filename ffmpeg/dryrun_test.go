@@ -0,0 +1,76 @@
+package ffmpeg
+
+import (
+  "os"
+  "reflect"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestDryRun(t *testing.T) {
+  dir, err := ioutil.TempDir("", "dryrun")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  in := filepath.Join(dir, "in.flac")
+  out := filepath.Join(dir, "out.mp3")
+
+  defer fakeFfprobe(t, 44100)()
+
+  d := NewDryRun()
+  d.Bin = "ffmpeg"
+
+  _, err = d.Transcode(&TranscodeConfig{ Input: in, Output: out,
+    Preset: "mp3-320", Normalize: &DefaultLoudness })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  c := d.Commands()
+  if len(c) != 3 {
+    t.Fatalf("Expected 3 commands, got %d", len(c))
+  }
+
+  // measure pass, encode to temp file, then rename to output
+  if c[0][0] != "ffmpeg" || !strings.Contains(strings.Join(c[0], " "),
+    "print_format=json -f null -") {
+    t.Errorf("Expected loudnorm measure pass, got %v", c[0])
+  }
+  tmp := filepath.Join(dir, ".out-tmp1.mp3")
+  if c[1][len(c[1])-1] != tmp {
+    t.Errorf("Expected %v as output, got %v", tmp, c[1][len(c[1])-1])
+  }
+  if e := []string{ "mv", tmp, out }; !reflect.DeepEqual(c[2], e) {
+    t.Errorf("Expected %v, got %v", e, c[2])
+  }
+
+  if _, err := os.Stat(out); !os.IsNotExist(err) {
+    t.Errorf("Expected no output written, got %v", err)
+  }
+
+  d.Reset()
+  if len(d.Commands()) != 0 {
+    t.Errorf("Expected no commands after reset")
+  }
+
+  // retag writes a temp file, not over its input
+  if err := ioutil.WriteFile(in, []byte{}, 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := d.WriteTags(in, Metadata{ Title: "Title" }, nil); err != nil {
+    t.Fatal(err)
+  }
+
+  c = d.Commands()
+  tmp = filepath.Join(dir, ".in-tmp1.flac")
+  if len(c) != 2 || c[0][len(c[0])-1] != tmp {
+    t.Fatalf("Expected command writing %v, got %v", tmp, c)
+  }
+  if e := []string{ "mv", tmp, in }; !reflect.DeepEqual(c[1], e) {
+    t.Errorf("Expected %v, got %v", e, c[1])
+  }
+}
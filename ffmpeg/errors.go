@@ -0,0 +1,111 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "errors"
+  "os/exec"
+  "strings"
+)
+
+// classified reason an ffmpeg run failed
+type Cause int
+
+const (
+  CauseUnknown Cause = iota
+  CauseInputNotFound
+  CauseUnsupportedCodec
+  CauseInvalidData
+  CausePermissionDenied
+  CauseDiskFull
+)
+
+func (c Cause) String() string {
+  switch c {
+  case CauseInputNotFound:
+    return "input not found"
+  case CauseUnsupportedCodec:
+    return "unsupported codec"
+  case CauseInvalidData:
+    return "invalid data"
+  case CausePermissionDenied:
+    return "permission denied"
+  case CauseDiskFull:
+    return "disk full"
+  }
+  return "unknown"
+}
+
+// stderr messages mapped to a cause, checked in order
+var causeMessages = []struct {
+  cause Cause
+  msgs []string
+}{
+  { CauseDiskFull, []string{ "No space left on device" } },
+  { CausePermissionDenied, []string{ "Permission denied" } },
+  { CauseInputNotFound, []string{ "No such file or directory" } },
+  { CauseUnsupportedCodec, []string{ "Unknown encoder", "Unknown decoder",
+    "Encoder not found", "Decoder not found", "Unsupported codec",
+    "not currently supported in container", "Could not find tag for codec" } },
+  { CauseInvalidData, []string{ "Invalid data found when processing input",
+    "moov atom not found" } },
+}
+
+// error returned when ffmpeg fails to run or exits non-zero
+type ExecError struct {
+  // arguments passed to ffmpeg
+  Args []string
+  // process exit code, -1 if the process did not exit normally
+  ExitCode int
+  // full stderr output
+  Stderr string
+  // classified from Stderr
+  Cause Cause
+  // underlying error; wraps ctx.Err() when canceled
+  Err error
+}
+
+func (e *ExecError) Error() string {
+  return fmt.Sprint(e.Err) + ": " + e.Stderr
+}
+
+func (e *ExecError) Unwrap() error {
+  return e.Err
+}
+
+// build ExecError from the error returned by exec.Cmd.Run
+func newExecError(args []string, stderr string, err error) *ExecError {
+  e := &ExecError{ Args: args, ExitCode: -1, Stderr: stderr,
+    Cause: classify(stderr), Err: err }
+
+  var exit *exec.ExitError
+  if errors.As(err, &exit) {
+    e.ExitCode = exit.ExitCode()
+  }
+  return e
+}
+
+// determine cause of failure from ffmpeg stderr
+func classify(stderr string) Cause {
+  for _, c := range causeMessages {
+    for _, m := range c.msgs {
+      if strings.Contains(stderr, m) {
+        return c.cause
+      }
+    }
+  }
+  return CauseUnknown
+}
+
+// error returned before running ffmpeg when the build lacks an encoder,
+// muxer or filter the arguments need
+type MissingError struct {
+  // "encoder", "muxer" or "filter"
+  Kind string
+  Name string
+  // ffmpeg version probed
+  Version string
+}
+
+func (e *MissingError) Error() string {
+  return fmt.Sprintf("ffmpeg %s has no %s %q", e.Version, e.Kind, e.Name)
+}
@@ -0,0 +1,62 @@
+package ffmpeg
+
+import (
+  "time"
+  "errors"
+  "context"
+  "testing"
+)
+
+func TestClassify(t *testing.T) {
+  tests := []struct {
+    stderr string
+    cause Cause
+  }{
+    { "missing.flac: No such file or directory", CauseInputNotFound },
+    { "Unknown encoder 'libmp3lame'", CauseUnsupportedCodec },
+    { "in.mp3: Invalid data found when processing input", CauseInvalidData },
+    { "/out/a.mp3: Permission denied", CausePermissionDenied },
+    { "av_interleaved_write_frame(): No space left on device", CauseDiskFull },
+    { "Conversion failed!", CauseUnknown },
+  }
+
+  for i := range tests {
+    c := classify(tests[i].stderr)
+    if c != tests[i].cause {
+      t.Errorf("Expected %v, got %v", tests[i].cause, c)
+    }
+  }
+}
+
+func TestExecError(t *testing.T) {
+  f := &ffmpeg{ Bin: "audiocc-bin-def-dne" }
+
+  _, err := f.Exec("-i", "in.flac")
+
+  var e *ExecError
+  if !errors.As(err, &e) {
+    t.Fatalf("Expected *ExecError, got %#v", err)
+  }
+  if e.ExitCode != -1 || len(e.Args) != 2 {
+    t.Errorf("Expected exit code -1 and 2 args, got %v and %v",
+      e.ExitCode, e.Args)
+  }
+}
+
+func TestExecContext(t *testing.T) {
+  f := &ffmpeg{ Bin: "sleep" }
+
+  ctx, cancel := context.WithTimeout(context.Background(),
+    200 * time.Millisecond)
+  defer cancel()
+
+  // child is killed at the deadline, not left to finish
+  start := time.Now()
+  _, err := f.ExecContext(ctx, "5")
+  if !errors.Is(err, context.DeadlineExceeded) {
+    t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+  }
+  if d := time.Since(start); d > 2 * time.Second {
+    t.Errorf("Expected sleep killed at deadline, ran %v", d)
+  }
+}
@@ -1,21 +1,37 @@
 package ffmpeg
 
 import (
+  "io"
   "os"
   "fmt"
+  "sync"
   "bytes"
   "errors"
+  "context"
+  "strings"
   "os/exec"
 )
 
 type Ffmpeger interface {
   ToMp3(c *Mp3Config) (string, error)
+  ToMp3Context(ctx context.Context, c *Mp3Config) (string, error)
   OptimizeAlbumArt(s, d string) (string, error)
+  OptimizeAlbumArtContext(ctx context.Context, s, d string) (string, error)
+  ExtractAlbumArt(input, output string) (*Picture, error)
+  ExtractAlbumArtContext(ctx context.Context, input,
+    output string) (*Picture, error)
   Exec(args ...string) (string, error)
+  ExecContext(ctx context.Context, args ...string) (string, error)
 }
 
 type ffmpeg struct {
   Bin string
+  // set for a dry run
+  dryRun *commandLog
+  // probed on first run
+  capsMu sync.Mutex
+  caps *Capabilities
+  capsFailed bool
 }
 
 type Metadata struct {
@@ -26,6 +42,22 @@ type Metadata struct {
   Title string
   Date string
   Artwork string
+  // below are only written when set
+  AlbumArtist string
+  Genre string
+  Comment string
+  Composer string
+  DiscTotal string
+  TrackTotal string
+  Compilation bool
+  MusicBrainzTrackId string
+  MusicBrainzAlbumId string
+  MusicBrainzArtistId string
+  MusicBrainzAlbumArtistId string
+  MusicBrainzReleaseGroupId string
+  // free-form tags by name, written as TXXX frames for id3v2 & upper cased
+  // for vorbis comments. not supported by mp4 & wav
+  Extra map[string]string
 }
 
 // new ffmpeg wrapper where args can be added
@@ -39,107 +71,147 @@ func New() (*ffmpeg, error) {
 
 // run ffmpeg (capture stdout & stderr)
 func (f *ffmpeg) Exec(args ...string) (string, error) {
-  exec := exec.Command(f.Bin, args...)
+  return f.ExecContext(context.Background(), args...)
+}
 
+// run ffmpeg, killing the process if ctx is canceled or its deadline passes.
+// errors are *ExecError; on cancellation it wraps ctx.Err()
+func (f *ffmpeg) ExecContext(ctx context.Context, args ...string) (string, error) {
   var out bytes.Buffer
+  _, err := f.run(ctx, nil, &out, args)
+  if err != nil {
+    return "", err
+  }
+  return out.String(), nil
+}
+
+// run ffmpeg reading stdin from in (may be nil) & writing stdout to out,
+// returns captured stderr. fails before running if the build lacks an
+// encoder, muxer or filter args need
+func (f *ffmpeg) run(ctx context.Context, in io.Reader, out io.Writer,
+  args []string) (string, error) {
+
+  err := f.require(ctx, args)
+  if err != nil {
+    return "", err
+  }
+
+  if f.dryRun != nil {
+    f.dryRun.record(f.Bin, args)
+    return "", nil
+  }
+
+  return f.execute(ctx, in, out, args)
+}
+
+// run ffmpeg process
+func (f *ffmpeg) execute(ctx context.Context, in io.Reader, out io.Writer,
+  args []string) (string, error) {
+
+  exec := exec.CommandContext(ctx, f.Bin, args...)
+
   var stderr bytes.Buffer
-  exec.Stdout = &out
+  exec.Stdin = in
+  exec.Stdout = out
   exec.Stderr = &stderr
 
   err := exec.Run()
+  if ctx.Err() != nil {
+    err = fmt.Errorf("ffmpeg: %w", ctx.Err())
+  }
   if err != nil {
-    return "", errors.New(fmt.Sprint(err) + ": " + stderr.String())
+    return stderr.String(), newExecError(args, stderr.String(), err)
   }
-  return out.String(), nil
+  return stderr.String(), nil
 }
 
 // optimize image as embedded album art
 func (f *ffmpeg) OptimizeAlbumArt(input, output string) (string, error) {
-  return f.Exec([]string{ "-i", input, "-y", "-qscale:v", "2",
-    "-vf", "scale=500:-1", output }...)
+  return f.OptimizeAlbumArtContext(context.Background(), input, output)
+}
+
+// optimize image as embedded album art, aborting if ctx is done
+func (f *ffmpeg) OptimizeAlbumArtContext(ctx context.Context,
+  input, output string) (string, error) {
+
+  return f.OptimizeArtworkContext(ctx, input, output, &ArtworkConfig{})
 }
 
 type Mp3Config struct {
   Input, Quality, Output string
   Meta Metadata
   Fix bool
+  // called as encoding advances; in Fix mode, once per pass
+  Progress ProgressFunc `json:"-"`
+  // input duration in seconds (ffprobe Format.Duration) used to compute
+  // percent complete; probed from Input when 0 and Progress is set
+  Duration float64
 }
 
-// mp3 quality helper function
-func (f *ffmpeg) mp3Quality(q string) []string {
-  a := []string{}
-
-  if q == "copy" {
-    a = append(a, "copy")
-  } else {
-    a = append(a, "libmp3lame")
-
-    // mp3 audio quality
-    if q == "320" {
-      a = append(a, "-b:a", "320k")
-    } else {
-      a = append(a, "-qscale:a", "0")
-    }
+// mp3 quality helper function. accepts "copy", "v0", "320" or the name of
+// any registered mp3 preset; empty is V0
+func (f *ffmpeg) mp3Quality(q string) (Quality, error) {
+  switch strings.ToLower(q) {
+  case "copy":
+    return Quality{ Copy: true }, nil
+  case "", "v0":
+    q = "mp3-v0"
+  case "320":
+    q = "mp3-320"
   }
 
-  return a
+  p, err := LookupPreset(q)
+  if err != nil || p.Codec != CodecMp3 {
+    return Quality{}, fmt.Errorf("unknown mp3 quality %q", q)
+  }
+  return p.Quality, nil
 }
 
 // convert to mp3
 func (f *ffmpeg) ToMp3(c *Mp3Config) (string, error) {
-  a := []string{ "-i" }
+  return f.ToMp3Context(context.Background(), c)
+}
+
+// convert to mp3, aborting if ctx is done
+func (f *ffmpeg) ToMp3Context(ctx context.Context, c *Mp3Config) (string, error) {
+  q, err := f.mp3Quality(c.Quality)
+  if err != nil {
+    return "", err
+  }
+
+  t := &TranscodeConfig{ Input: c.Input, Output: c.Output, Codec: CodecMp3,
+    Quality: q, Meta: c.Meta, Progress: c.Progress, Duration: c.Duration }
+
+  if t.Progress != nil && t.Duration == 0 {
+    t.Duration = probeDuration(ctx, c.Input)
+  }
 
   // if track length displays outrageous number like 1035:36:51
   // copy w/o metadata, then add metadata fixes it
-  fixOut := c.Output[:len(c.Output)-4] + "-fix.mp3"
   if c.Fix {
-    b := []string{ "-i", c.Input, "-map_metadata", "-1", "-c:a" }
-    b = append(b, f.mp3Quality(c.Quality)...)
-    b = append(b, "-y", fixOut)
+    fixOut, err := f.tempFileFor(c.Output)
+    if err != nil {
+      return "", err
+    }
+    if f.dryRun == nil {
+      defer os.Remove(fixOut)
+    }
+
+    b := []string{ "-i", c.Input, "-map_metadata", "-1" }
+    b = append(b, codecArgs(CodecMp3, t.Quality)...)
+    b = append(b, "-f", "mp3", "-y", fixOut)
 
-    s, err := f.Exec(b...)
+    s, err := f.execProgress(ctx, t.Duration, t.Progress, b...)
     if err != nil {
       return s, err
     }
 
     // next input is fixed output
-    a = append(a, fixOut)
+    t.Input = fixOut
 
     // do not need to convert again
-    c.Quality = "copy"
-  } else {
-    a = append(a, c.Input)
-  }
-
-  if len(c.Meta.Artwork) > 0 {
-    a = append(a, "-i", c.Meta.Artwork)
-  }
-
-  // mp3 audio codec
-  a = append(a, "-map", "0:a", "-c:a")
-  a = append(a, f.mp3Quality(c.Quality)...)
-
-  // id3v2 metadata
-  a = append(a, "-id3v2_version", "4")
-  a = append(a, "-metadata", "artist=" + c.Meta.Artist)
-  a = append(a, "-metadata", "album=" + c.Meta.Album)
-  a = append(a, "-metadata", "disc=" + c.Meta.Disc)
-  a = append(a, "-metadata", "track=" + c.Meta.Track)
-  a = append(a, "-metadata", "title=" + c.Meta.Title)
-  a = append(a, "-metadata", "date=" + c.Meta.Date)
-
-  // embedd album artwork
-  if len(c.Meta.Artwork) > 0 {
-    a = append(a, "-map", "1:v", "-c:v", "copy", "-metadata:s:v",
-      "title=Album cover", "-metadata:s:v", "comment=Cover (Front)")
-  }
-
-  a = append(a, "-y", c.Output)
-  s, err := f.Exec(a...)
-
-  if c.Fix && err == nil {
-    err = os.Remove(fixOut)
+    t.Quality = Quality{ Copy: true }
   }
 
-  return s, err
+  return f.TranscodeContext(ctx, t)
 }
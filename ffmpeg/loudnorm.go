@@ -0,0 +1,138 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "errors"
+  "context"
+  "strings"
+  "io/ioutil"
+  "encoding/json"
+)
+
+// EBU R128 loudness target
+type Loudness struct {
+  // integrated loudness in LUFS (-70 to -5)
+  Integrated float64
+  // maximum true peak in dBTP (-9 to 0)
+  TruePeak float64
+  // loudness range in LU (1 to 20)
+  Range float64
+}
+
+// EBU R128 broadcast target with 1 dB true peak headroom
+var DefaultLoudness = Loudness{ Integrated: -23, TruePeak: -1, Range: 11 }
+
+// measurements printed by the loudnorm filter
+type LoudnessStats struct {
+  InputI float64 `json:"input_i,string"`
+  InputTP float64 `json:"input_tp,string"`
+  InputLRA float64 `json:"input_lra,string"`
+  InputThresh float64 `json:"input_thresh,string"`
+  OutputI float64 `json:"output_i,string"`
+  OutputTP float64 `json:"output_tp,string"`
+  OutputLRA float64 `json:"output_lra,string"`
+  OutputThresh float64 `json:"output_thresh,string"`
+  NormalizationType string `json:"normalization_type"`
+  TargetOffset float64 `json:"target_offset,string"`
+}
+
+// measure loudness of input (1st loudnorm pass)
+func (f *ffmpeg) MeasureLoudness(input string,
+  target Loudness) (*LoudnessStats, error) {
+
+  return f.MeasureLoudnessContext(context.Background(), input, target)
+}
+
+// measure loudness of input (1st loudnorm pass), aborting if ctx is done
+func (f *ffmpeg) MeasureLoudnessContext(ctx context.Context, input string,
+  target Loudness) (*LoudnessStats, error) {
+
+  return f.measureLoudness(ctx, []string{ "-i", input }, target, nil)
+}
+
+// measure loudness of input (given as ffmpeg input arguments) after filters
+func (f *ffmpeg) measureLoudness(ctx context.Context, in []string,
+  target Loudness, filters []string) (*LoudnessStats, error) {
+
+  err := target.validate()
+  if err != nil {
+    return nil, err
+  }
+
+  af := append(append([]string{}, filters...),
+    target.filter() + ":print_format=json")
+  a := append([]string{ "-hide_banner", "-nostats" }, in...)
+  a = append(a, "-map", "0:a", "-af", strings.Join(af, ","),
+    "-f", "null", "-")
+
+  stderr, err := f.run(ctx, nil, ioutil.Discard, a)
+  if err != nil {
+    return nil, err
+  }
+  if f.dryRun != nil {
+    return &LoudnessStats{}, nil
+  }
+
+  return parseLoudnessStats(stderr)
+}
+
+// two-pass loudness normalization of c.Input into c.Output. uses
+// DefaultLoudness when c.Normalize is nil. returns 1st pass measurements
+func (f *ffmpeg) Normalize(c *TranscodeConfig) (*LoudnessStats, error) {
+  return f.NormalizeContext(context.Background(), c)
+}
+
+// two-pass loudness normalization, aborting if ctx is done
+func (f *ffmpeg) NormalizeContext(ctx context.Context,
+  c *TranscodeConfig) (*LoudnessStats, error) {
+
+  if c.Normalize == nil {
+    t := DefaultLoudness
+    c.Normalize = &t
+  }
+
+  _, stats, err := f.transcode(ctx, c)
+  return stats, err
+}
+
+func (l Loudness) validate() error {
+  if l.Integrated < -70 || l.Integrated > -5 {
+    return errors.New("integrated loudness must be -70 to -5 LUFS")
+  }
+  if l.TruePeak < -9 || l.TruePeak > 0 {
+    return errors.New("true peak must be -9 to 0 dBTP")
+  }
+  if l.Range < 1 || l.Range > 20 {
+    return errors.New("loudness range must be 1 to 20 LU")
+  }
+  return nil
+}
+
+// loudnorm filter with target settings
+func (l Loudness) filter() string {
+  return fmt.Sprintf("loudnorm=I=%v:TP=%v:LRA=%v",
+    l.Integrated, l.TruePeak, l.Range)
+}
+
+// loudnorm filter for 2nd (linear) pass using 1st pass measurements
+func (l Loudness) linearFilter(s *LoudnessStats) string {
+  return fmt.Sprintf("%v:measured_I=%v:measured_TP=%v:measured_LRA=%v:" +
+    "measured_thresh=%v:offset=%v:linear=true", l.filter(), s.InputI,
+    s.InputTP, s.InputLRA, s.InputThresh, s.TargetOffset)
+}
+
+// extract json block loudnorm prints at end of stderr
+func parseLoudnessStats(stderr string) (*LoudnessStats, error) {
+  start := strings.LastIndex(stderr, "{")
+  end := strings.LastIndex(stderr, "}")
+  if start < 0 || end < start {
+    return nil, errors.New("loudnorm stats not found in ffmpeg output")
+  }
+
+  s := &LoudnessStats{}
+  err := json.Unmarshal([]byte(stderr[start:end+1]), s)
+  if err != nil {
+    return nil, fmt.Errorf("invalid loudnorm stats: %v", err)
+  }
+  return s, nil
+}
@@ -0,0 +1,48 @@
+package ffmpeg
+
+import (
+  "testing"
+)
+
+func TestParseLoudnessStats(t *testing.T) {
+  stderr := `Input #0, flac, from 'in.flac':
+  Duration: 00:05:12.00, start: 0.000000, bitrate: 901 kb/s
+[Parsed_loudnorm_0 @ 0x55d5c3e6c2c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-23.05",
+	"output_tp" : "-1.00",
+	"output_lra" : "10.20",
+	"output_thresh" : "-34.12",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.05"
+}
+`
+
+  s, err := parseLoudnessStats(stderr)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  exp := LoudnessStats{ InputI: -27.61, InputTP: -4.47, InputLRA: 18.06,
+    InputThresh: -39.2, OutputI: -23.05, OutputTP: -1, OutputLRA: 10.2,
+    OutputThresh: -34.12, NormalizationType: "dynamic", TargetOffset: 0.05 }
+  if *s != exp {
+    t.Errorf("Expected %+v, got %+v", exp, *s)
+  }
+
+  f := DefaultLoudness.linearFilter(s)
+  expF := "loudnorm=I=-23:TP=-1:LRA=11:measured_I=-27.61:measured_TP=-4.47:" +
+    "measured_LRA=18.06:measured_thresh=-39.2:offset=0.05:linear=true"
+  if f != expF {
+    t.Errorf("Expected %v, got %v", expF, f)
+  }
+
+  _, err = parseLoudnessStats("Conversion failed!")
+  if err == nil {
+    t.Errorf("Expected error when stats missing")
+  }
+}
@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+  "sort"
+  "errors"
+  "strings"
+)
+
+// Metadata field identifiers
+const (
+  tagArtist = iota
+  tagAlbum
+  tagAlbumArtist
+  tagDisc
+  tagDiscTotal
+  tagTrack
+  tagTrackTotal
+  tagTitle
+  tagDate
+  tagGenre
+  tagComment
+  tagComposer
+  tagCompilation
+  tagMbTrack
+  tagMbAlbum
+  tagMbArtist
+  tagMbAlbumArtist
+  tagMbReleaseGroup
+)
+
+// tag names per container, fields without a name are not written. id3v2 &
+// mp4 store totals within track & disc ("3/12"). ffmpeg writes unknown id3v2
+// names as TXXX frames; it can not write mp4 freeform atoms so MusicBrainz
+// ids & extra tags are rejected for mp4 (see supported)
+var tagNames = map[tagStyle]map[int]string{
+  tagsId3: {
+    tagArtist: "artist", tagAlbum: "album", tagAlbumArtist: "album_artist",
+    tagDisc: "disc", tagTrack: "track", tagTitle: "title", tagDate: "date",
+    tagGenre: "genre", tagComment: "comment", tagComposer: "composer",
+    tagCompilation: "compilation", tagMbTrack: "MusicBrainz Track Id",
+    tagMbAlbum: "MusicBrainz Album Id", tagMbArtist: "MusicBrainz Artist Id",
+    tagMbAlbumArtist: "MusicBrainz Album Artist Id",
+    tagMbReleaseGroup: "MusicBrainz Release Group Id",
+  },
+  tagsVorbis: {
+    tagArtist: "ARTIST", tagAlbum: "ALBUM", tagAlbumArtist: "ALBUMARTIST",
+    tagDisc: "DISCNUMBER", tagDiscTotal: "DISCTOTAL",
+    tagTrack: "TRACKNUMBER", tagTrackTotal: "TRACKTOTAL", tagTitle: "TITLE",
+    tagDate: "DATE", tagGenre: "GENRE", tagComment: "COMMENT",
+    tagComposer: "COMPOSER", tagCompilation: "COMPILATION",
+    tagMbTrack: "MUSICBRAINZ_TRACKID", tagMbAlbum: "MUSICBRAINZ_ALBUMID",
+    tagMbArtist: "MUSICBRAINZ_ARTISTID",
+    tagMbAlbumArtist: "MUSICBRAINZ_ALBUMARTISTID",
+    tagMbReleaseGroup: "MUSICBRAINZ_RELEASEGROUPID",
+  },
+  tagsMp4: {
+    tagArtist: "artist", tagAlbum: "album", tagAlbumArtist: "album_artist",
+    tagDisc: "disc", tagTrack: "track", tagTitle: "title", tagDate: "date",
+    tagGenre: "genre", tagComment: "comment", tagComposer: "composer",
+    tagCompilation: "compilation",
+  },
+  // RIFF INFO has no disc number
+  tagsRiff: {
+    tagArtist: "artist", tagAlbum: "album", tagTrack: "track",
+    tagTitle: "title", tagDate: "date", tagGenre: "genre",
+    tagComment: "comment",
+  },
+}
+
+// original fields, always written so empty values clear existing tags
+var tagsAlways = map[int]bool{ tagArtist: true, tagAlbum: true, tagDisc: true,
+  tagTrack: true, tagTitle: true, tagDate: true }
+
+// field values in tag order. when clear, empty original fields are included
+// so existing tags are removed
+func (m Metadata) values(style tagStyle, clear bool) [][2]string {
+  disc, track := m.Disc, m.Track
+
+  // totals combined as "n/total"
+  if style == tagsId3 || style == tagsMp4 {
+    if len(m.DiscTotal) > 0 && len(disc) > 0 {
+      disc += "/" + m.DiscTotal
+    }
+    if len(m.TrackTotal) > 0 && len(track) > 0 {
+      track += "/" + m.TrackTotal
+    }
+  }
+
+  compilation := ""
+  if m.Compilation {
+    compilation = "1"
+  }
+
+  v := map[int]string{ tagArtist: m.Artist, tagAlbum: m.Album,
+    tagAlbumArtist: m.AlbumArtist, tagDisc: disc, tagDiscTotal: m.DiscTotal,
+    tagTrack: track, tagTrackTotal: m.TrackTotal, tagTitle: m.Title,
+    tagDate: m.Date, tagGenre: m.Genre, tagComment: m.Comment,
+    tagComposer: m.Composer, tagCompilation: compilation,
+    tagMbTrack: m.MusicBrainzTrackId, tagMbAlbum: m.MusicBrainzAlbumId,
+    tagMbArtist: m.MusicBrainzArtistId,
+    tagMbAlbumArtist: m.MusicBrainzAlbumArtistId,
+    tagMbReleaseGroup: m.MusicBrainzReleaseGroupId }
+
+  tags := [][2]string{}
+  for f := tagArtist; f <= tagMbReleaseGroup; f++ {
+    name, ok := tagNames[style][f]
+    if !ok || (len(v[f]) == 0 && !(clear && tagsAlways[f])) {
+      continue
+    }
+    tags = append(tags, [2]string{ name, v[f] })
+  }
+
+  // extra tags sorted by name for stable output
+  if style == tagsId3 || style == tagsVorbis {
+    names := make([]string, 0, len(m.Extra))
+    for k := range m.Extra {
+      names = append(names, k)
+    }
+    sort.Strings(names)
+
+    for _, k := range names {
+      name := k
+      if style == tagsVorbis {
+        name = strings.ToUpper(k)
+      }
+      tags = append(tags, [2]string{ name, m.Extra[k] })
+    }
+  }
+
+  return tags
+}
+
+// error when m sets MusicBrainz ids or extra tags a mp4 or wav container can
+// not store, rather than silently dropping them
+func (m Metadata) supported(style tagStyle) error {
+  if style != tagsMp4 && style != tagsRiff {
+    return nil
+  }
+  if len(m.MusicBrainzTrackId) > 0 || len(m.MusicBrainzAlbumId) > 0 ||
+    len(m.MusicBrainzArtistId) > 0 || len(m.MusicBrainzAlbumArtistId) > 0 ||
+    len(m.MusicBrainzReleaseGroupId) > 0 {
+    return errors.New("MusicBrainz ids can not be written to mp4 or wav")
+  }
+  if len(m.Extra) > 0 {
+    return errors.New("extra tags can not be written to mp4 or wav")
+  }
+  return nil
+}
+
+// tag arguments for container
+func metadataArgs(style tagStyle, m Metadata) []string {
+  return tagArgs(style, m.values(style, true))
+}
+
+// tag arguments for container, leaving tags for empty fields untouched
+func mergeMetadataArgs(style tagStyle, m Metadata) []string {
+  return tagArgs(style, m.values(style, false))
+}
+
+func tagArgs(style tagStyle, tags [][2]string) []string {
+  a := []string{}
+  if style == tagsId3 {
+    a = append(a, "-id3v2_version", "4")
+  }
+  for _, t := range tags {
+    a = append(a, "-metadata", t[0] + "=" + t[1])
+  }
+  return a
+}
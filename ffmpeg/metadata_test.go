@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestMetadataArgs(t *testing.T) {
+  m := Metadata{ Artist: "Artist", Album: "Album", Disc: "1", Track: "3",
+    Title: "Title", Date: "1977", AlbumArtist: "Various", Genre: "Rock",
+    Composer: "Composer", DiscTotal: "2", TrackTotal: "12", Compilation: true,
+    MusicBrainzAlbumId: "a1b2", Extra: map[string]string{ "taper": "Miller",
+    "source": "SBD" } }
+
+  tests := []struct {
+    style tagStyle
+    args string
+  }{
+    { tagsId3, "-id3v2_version 4 -metadata artist=Artist -metadata album=Album " +
+      "-metadata album_artist=Various -metadata disc=1/2 -metadata track=3/12 " +
+      "-metadata title=Title -metadata date=1977 -metadata genre=Rock " +
+      "-metadata composer=Composer -metadata compilation=1 " +
+      "-metadata MusicBrainz Album Id=a1b2 -metadata source=SBD " +
+      "-metadata taper=Miller" },
+    { tagsVorbis, "-metadata ARTIST=Artist -metadata ALBUM=Album " +
+      "-metadata ALBUMARTIST=Various -metadata DISCNUMBER=1 " +
+      "-metadata DISCTOTAL=2 -metadata TRACKNUMBER=3 -metadata TRACKTOTAL=12 " +
+      "-metadata TITLE=Title -metadata DATE=1977 -metadata GENRE=Rock " +
+      "-metadata COMPOSER=Composer -metadata COMPILATION=1 " +
+      "-metadata MUSICBRAINZ_ALBUMID=a1b2 -metadata SOURCE=SBD " +
+      "-metadata TAPER=Miller" },
+    { tagsMp4, "-metadata artist=Artist -metadata album=Album " +
+      "-metadata album_artist=Various -metadata disc=1/2 -metadata track=3/12 " +
+      "-metadata title=Title -metadata date=1977 -metadata genre=Rock " +
+      "-metadata composer=Composer -metadata compilation=1" },
+    { tagsRiff, "-metadata artist=Artist -metadata album=Album " +
+      "-metadata track=3 -metadata title=Title -metadata date=1977 " +
+      "-metadata genre=Rock" },
+  }
+
+  for i := range tests {
+    a := strings.Join(metadataArgs(tests[i].style, m), " ")
+    if a != tests[i].args {
+      t.Errorf("Expected %v, got %v", tests[i].args, a)
+    }
+  }
+}
+
+func TestMetadataSupported(t *testing.T) {
+  tests := []struct {
+    style tagStyle
+    m Metadata
+    err bool
+  }{
+    { tagsMp4, Metadata{ Artist: "Artist", Comment: "SBD" }, false },
+    { tagsMp4, Metadata{ MusicBrainzTrackId: "a1b2" }, true },
+    { tagsMp4, Metadata{ Extra: map[string]string{ "taper": "Miller" } }, true },
+    { tagsRiff, Metadata{ MusicBrainzReleaseGroupId: "a1b2" }, true },
+    { tagsId3, Metadata{ MusicBrainzTrackId: "a1b2",
+      Extra: map[string]string{ "taper": "Miller" } }, false },
+    { tagsVorbis, Metadata{ Extra: map[string]string{ "taper": "Miller" } },
+      false },
+  }
+
+  for i := range tests {
+    err := tests[i].m.supported(tests[i].style)
+    if (err != nil) != tests[i].err {
+      t.Errorf("Test %d: expected error %v, got %v", i, tests[i].err, err)
+    }
+  }
+}
@@ -0,0 +1,110 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "sort"
+  "sync"
+  "strings"
+)
+
+// named codec & quality combination
+type Preset struct {
+  Name string
+  Codec Codec
+  Quality Quality
+}
+
+var presets = struct {
+  sync.RWMutex
+  m map[string]*Preset
+}{ m: map[string]*Preset{} }
+
+func init() {
+  for _, p := range []*Preset{
+    { Name: "mp3-v0", Codec: CodecMp3, Quality: Quality{ Vbr: 0 } },
+    { Name: "mp3-v2", Codec: CodecMp3, Quality: Quality{ Vbr: 2 } },
+    { Name: "mp3-320", Codec: CodecMp3, Quality: Quality{ Bitrate: 320 } },
+    { Name: "opus-96", Codec: CodecOpus, Quality: Quality{ Bitrate: 96 } },
+    { Name: "opus-128", Codec: CodecOpus, Quality: Quality{ Bitrate: 128 } },
+    { Name: "flac-5", Codec: CodecFlac, Quality: Quality{ Compression: 5 } },
+    { Name: "flac-8", Codec: CodecFlac, Quality: Quality{ Compression: 8 } },
+    { Name: "aac-256", Codec: CodecAac, Quality: Quality{ Bitrate: 256 } },
+    { Name: "vorbis-q6", Codec: CodecVorbis, Quality: Quality{ Vbr: 6 } },
+    { Name: "wav", Codec: CodecWav },
+  } {
+    presets.m[p.Name] = p
+  }
+}
+
+// add a preset to the registry. names are case insensitive and may not
+// replace an existing preset
+func RegisterPreset(p Preset) error {
+  p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+  if len(p.Name) == 0 {
+    return fmt.Errorf("preset name required")
+  }
+
+  err := p.Quality.validate(p.Codec)
+  if err != nil {
+    return fmt.Errorf("preset %q: %v", p.Name, err)
+  }
+
+  presets.Lock()
+  defer presets.Unlock()
+
+  if _, found := presets.m[p.Name]; found {
+    return fmt.Errorf("preset %q already registered", p.Name)
+  }
+  presets.m[p.Name] = &p
+  return nil
+}
+
+// find preset by name
+func LookupPreset(name string) (Preset, error) {
+  presets.RLock()
+  defer presets.RUnlock()
+
+  p, found := presets.m[strings.ToLower(strings.TrimSpace(name))]
+  if !found {
+    return Preset{}, fmt.Errorf("unknown preset %q", name)
+  }
+  return *p, nil
+}
+
+// all registered presets sorted by name
+func Presets() []Preset {
+  presets.RLock()
+  defer presets.RUnlock()
+
+  list := make([]Preset, 0, len(presets.m))
+  for _, p := range presets.m {
+    list = append(list, *p)
+  }
+
+  sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+  return list
+}
+
+// check quality settings are within range for codec
+func (q Quality) validate(codec Codec) error {
+  if _, ok := codecs[codec]; !ok {
+    return fmt.Errorf("unsupported codec %q", codec)
+  }
+
+  if q.Bitrate < 0 || q.Vbr < 0 || q.Compression < 0 {
+    return fmt.Errorf("negative quality value")
+  }
+
+  switch {
+  case codec == CodecMp3 && q.Vbr > 9:
+    return fmt.Errorf("mp3 vbr level must be 0-9")
+  case codec == CodecVorbis && q.Vbr > 10:
+    return fmt.Errorf("vorbis vbr level must be 0-10")
+  case codec == CodecAac && q.Vbr > 5:
+    return fmt.Errorf("aac vbr mode must be 1-5")
+  case codec == CodecFlac && q.Compression > 12:
+    return fmt.Errorf("flac compression level must be 0-12")
+  }
+
+  return nil
+}
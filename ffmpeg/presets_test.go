@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+  "testing"
+)
+
+func TestRegisterPreset(t *testing.T) {
+  // unregister so the test can run again in the same binary
+  defer func() {
+    presets.Lock()
+    delete(presets.m, "opus-64")
+    presets.Unlock()
+  }()
+
+  tests := []struct {
+    preset Preset
+    valid bool
+  }{
+    { Preset{ Name: "Opus-64", Codec: CodecOpus, Quality: Quality{ Bitrate: 64 } }, true },
+    { Preset{ Name: "opus-64", Codec: CodecOpus }, false },
+    { Preset{ Name: "", Codec: CodecOpus }, false },
+    { Preset{ Name: "wma-128", Codec: "wma" }, false },
+    { Preset{ Name: "mp3-v10", Codec: CodecMp3, Quality: Quality{ Vbr: 10 } }, false },
+    { Preset{ Name: "flac-13", Codec: CodecFlac, Quality: Quality{ Compression: 13 } }, false },
+  }
+
+  for i := range tests {
+    err := RegisterPreset(tests[i].preset)
+    if (err == nil) != tests[i].valid {
+      t.Errorf("%v: expected valid %v, got %v", tests[i].preset.Name,
+        tests[i].valid, err)
+    }
+  }
+
+  p, err := LookupPreset("OPUS-64")
+  if err != nil || p.Quality.Bitrate != 64 {
+    t.Errorf("Expected registered preset, got %+v, %v", p, err)
+  }
+
+  found := false
+  for _, p := range Presets() {
+    if p.Name == "opus-64" {
+      found = true
+    }
+  }
+  if !found {
+    t.Errorf("Expected opus-64 in preset list")
+  }
+}
+
+func TestMp3Quality(t *testing.T) {
+  tests := []struct {
+    quality string
+    result Quality
+    valid bool
+  }{
+    { "copy", Quality{ Copy: true }, true },
+    { "", Quality{}, true },
+    { "V0", Quality{}, true },
+    { "320", Quality{ Bitrate: 320 }, true },
+    { "mp3-v2", Quality{ Vbr: 2 }, true },
+    { "flac-8", Quality{}, false },
+    { "256", Quality{}, false },
+  }
+
+  f := &ffmpeg{}
+  for i := range tests {
+    q, err := f.mp3Quality(tests[i].quality)
+    if (err == nil) != tests[i].valid || q != tests[i].result {
+      t.Errorf("%q: expected %+v, got %+v (%v)", tests[i].quality,
+        tests[i].result, q, err)
+    }
+  }
+}
@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "context"
+
+  "github.com/jamlib/libaudio/ffprobe"
+)
+
+// input duration in seconds via ffprobe, 0 if unable to determine
+func probeDuration(ctx context.Context, input string) float64 {
+  p, err := ffprobe.New()
+  if err != nil {
+    return 0
+  }
+
+  d, err := p.GetDataContext(ctx, input)
+  if err != nil || d.Format == nil {
+    return 0
+  }
+  return d.Format.Duration
+}
+
+// first audio stream of input via ffprobe
+func audioStream(ctx context.Context, input string) (*ffprobe.Stream, error) {
+  p, err := ffprobe.New()
+  if err != nil {
+    return nil, err
+  }
+
+  d, err := p.GetDataContext(ctx, input)
+  if err != nil {
+    return nil, fmt.Errorf("%v: %v", input, err)
+  }
+
+  for _, s := range d.Streams {
+    if s.CodecType == "audio" {
+      return s, nil
+    }
+  }
+  return nil, fmt.Errorf("no audio stream in %v", input)
+}
+
+// attached picture (embedded artwork) stream of input via ffprobe
+func pictureStream(ctx context.Context, input string) (*ffprobe.Stream, error) {
+  p, err := ffprobe.New()
+  if err != nil {
+    return nil, err
+  }
+
+  d, err := p.GetDataContext(ctx, input)
+  if err != nil {
+    return nil, fmt.Errorf("%v: %v", input, err)
+  }
+
+  for _, s := range d.Streams {
+    if s.CodecType == "video" && s.Disposition != nil &&
+      s.Disposition.AttachedPic == 1 {
+      return s, nil
+    }
+  }
+  return nil, fmt.Errorf("no embedded image in %v", input)
+}
@@ -0,0 +1,124 @@
+package ffmpeg
+
+import (
+  "time"
+  "bytes"
+  "context"
+  "strconv"
+  "strings"
+)
+
+// encoding progress parsed from ffmpeg's -progress output
+type Progress struct {
+  // position within the output
+  OutTime time.Duration
+  // encoding speed relative to realtime (1.0 = realtime)
+  Speed float64
+  // bytes written to output so far
+  TotalSize int64
+  // input duration, 0 if unknown
+  Duration time.Duration
+  // 0-100, 0 if Duration is unknown
+  Percent float64
+  // estimated time remaining, 0 if unknown
+  Eta time.Duration
+  // true on the final report
+  Done bool
+}
+
+type ProgressFunc func(p *Progress)
+
+// run ffmpeg reporting progress to fn. if fn is nil, same as ExecContext
+func (f *ffmpeg) execProgress(ctx context.Context, duration float64,
+  fn ProgressFunc, args ...string) (string, error) {
+
+  if fn == nil {
+    return f.ExecContext(ctx, args...)
+  }
+
+  w := &progressWriter{ fn: fn,
+    p: Progress{ Duration: seconds(duration) } }
+
+  a := append([]string{ "-progress", "pipe:1", "-nostats" }, args...)
+  _, err := f.run(ctx, nil, w, a)
+  return "", err
+}
+
+// parses key=value lines written by ffmpeg -progress, calling fn at the
+// end of each block (marked by a progress= line)
+type progressWriter struct {
+  buf []byte
+  p Progress
+  fn ProgressFunc
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+  w.buf = append(w.buf, b...)
+
+  for {
+    i := bytes.IndexByte(w.buf, '\n')
+    if i < 0 {
+      break
+    }
+    w.line(strings.TrimSpace(string(w.buf[:i])))
+    w.buf = w.buf[i+1:]
+  }
+
+  return len(b), nil
+}
+
+func (w *progressWriter) line(l string) {
+  kv := strings.SplitN(l, "=", 2)
+  if len(kv) != 2 {
+    return
+  }
+  k, v := kv[0], strings.TrimSpace(kv[1])
+
+  switch k {
+  // out_time_ms is also reported in microseconds
+  case "out_time_us", "out_time_ms":
+    if us, err := strconv.ParseInt(v, 10, 64); err == nil && us >= 0 {
+      w.p.OutTime = time.Duration(us) * time.Microsecond
+    }
+  case "total_size":
+    if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+      w.p.TotalSize = n
+    }
+  case "speed":
+    if n, err := strconv.ParseFloat(strings.TrimSuffix(v, "x"), 64); err == nil {
+      w.p.Speed = n
+    }
+  case "progress":
+    w.p.Done = v == "end"
+    w.update()
+
+    p := w.p
+    w.fn(&p)
+  }
+}
+
+// compute percent complete & eta from out time, duration & speed
+func (w *progressWriter) update() {
+  w.p.Percent, w.p.Eta = 0, 0
+
+  if w.p.Done {
+    if w.p.Duration > 0 {
+      w.p.Percent = 100
+    }
+    return
+  }
+
+  if w.p.Duration <= 0 {
+    return
+  }
+
+  w.p.Percent = float64(w.p.OutTime) / float64(w.p.Duration) * 100
+  if w.p.Percent > 100 {
+    w.p.Percent = 100
+  }
+
+  remain := w.p.Duration - w.p.OutTime
+  if w.p.Speed > 0 && remain > 0 {
+    w.p.Eta = time.Duration(float64(remain) / w.p.Speed)
+  }
+}
@@ -0,0 +1,38 @@
+package ffmpeg
+
+import (
+  "time"
+  "testing"
+)
+
+func TestProgressWriter(t *testing.T) {
+  results := []Progress{}
+  w := &progressWriter{ p: Progress{ Duration: 100 * time.Second },
+    fn: func(p *Progress) { results = append(results, *p) } }
+
+  // split writes across line boundaries
+  writes := []string{
+    "out_time_us=25000000\ntotal_size=1024\nsp",
+    "eed=2.5x\nprogress=continue\n",
+    "out_time_ms=100000000\ntotal_size=4096\nspeed=N/A\nprogress=end\n",
+  }
+  for i := range writes {
+    _, _ = w.Write([]byte(writes[i]))
+  }
+
+  expected := []Progress{
+    { OutTime: 25 * time.Second, Speed: 2.5, TotalSize: 1024,
+      Duration: 100 * time.Second, Percent: 25, Eta: 30 * time.Second },
+    { OutTime: 100 * time.Second, Speed: 2.5, TotalSize: 4096,
+      Duration: 100 * time.Second, Percent: 100, Done: true },
+  }
+
+  if len(results) != len(expected) {
+    t.Fatalf("Expected %v reports, got %v", len(expected), len(results))
+  }
+  for i := range expected {
+    if results[i] != expected[i] {
+      t.Errorf("Expected %+v, got %+v", expected[i], results[i])
+    }
+  }
+}
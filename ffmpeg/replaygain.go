@@ -0,0 +1,203 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "math"
+  "regexp"
+  "context"
+  "strconv"
+  "strings"
+  "io/ioutil"
+  "path/filepath"
+
+  "github.com/jamlib/libaudio/fsutil"
+)
+
+// ReplayGain 2.0 reference loudness in LUFS
+const ReplayGainReference = -18.0
+
+// gain in dB & linear sample peak for a track & its album
+type ReplayGain struct {
+  TrackGain, TrackPeak float64
+  AlbumGain, AlbumPeak float64
+}
+
+var (
+  regexpEbur128I = regexp.MustCompile(`I:\s+(\S+) LUFS`)
+  regexpEbur128Peak = regexp.MustCompile(`Peak:\s+(\S+) dBFS`)
+)
+
+// REPLAYGAIN_* tags in the format written by common taggers
+func (r *ReplayGain) Tags() [][2]string {
+  return [][2]string{
+    { "REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", r.TrackGain) },
+    { "REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", r.TrackPeak) },
+    { "REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", r.AlbumGain) },
+    { "REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", r.AlbumPeak) },
+  }
+}
+
+// analyze files as a single album, returns ReplayGain for each file in order
+func (f *ffmpeg) ReplayGain(files []string) ([]*ReplayGain, error) {
+  return f.ReplayGainContext(context.Background(), files)
+}
+
+// analyze files as a single album, aborting if ctx is done
+func (f *ffmpeg) ReplayGainContext(ctx context.Context,
+  files []string) ([]*ReplayGain, error) {
+
+  rg := make([]*ReplayGain, len(files))
+  if len(files) == 0 {
+    return rg, nil
+  }
+
+  albumPeak := 0.0
+  for i := range files {
+    loudness, peak, err := f.ebur128(ctx, []string{ "-i", files[i],
+      "-map", "0:a", "-af", "ebur128=peak=sample" })
+    if err != nil {
+      return rg, err
+    }
+
+    rg[i] = &ReplayGain{ TrackGain: ReplayGainReference - loudness,
+      TrackPeak: peak }
+    albumPeak = math.Max(albumPeak, peak)
+  }
+
+  // album loudness is measured over all files joined end to end
+  albumGain := rg[0].TrackGain
+  if len(files) > 1 {
+    a := []string{}
+    inputs := ""
+    for i := range files {
+      a = append(a, "-i", files[i])
+      inputs += fmt.Sprintf("[%d:a]", i)
+    }
+
+    loudness, _, err := f.ebur128(ctx, append(a, "-filter_complex",
+      fmt.Sprintf("%sconcat=n=%d:v=0:a=1,ebur128=peak=sample", inputs,
+      len(files))))
+    if err != nil {
+      return rg, err
+    }
+    albumGain = ReplayGainReference - loudness
+  }
+
+  for i := range rg {
+    rg[i].AlbumGain, rg[i].AlbumPeak = albumGain, albumPeak
+  }
+  return rg, nil
+}
+
+// analyze files relative to dir, treating each directory as an album
+// (see fsutil.BundleFiles). returns ReplayGain for each file in order
+func (f *ffmpeg) ReplayGainBundles(dir string,
+  files []string) ([]*ReplayGain, error) {
+
+  return f.ReplayGainBundlesContext(context.Background(), dir, files)
+}
+
+// analyze album bundles, aborting if ctx is done
+func (f *ffmpeg) ReplayGainBundlesContext(ctx context.Context, dir string,
+  files []string) ([]*ReplayGain, error) {
+
+  rg := make([]*ReplayGain, len(files))
+
+  err := fsutil.BundleFiles(dir, files, func(bundle []int) error {
+    paths := make([]string, len(bundle))
+    for i := range bundle {
+      paths[i] = filepath.Join(dir, files[bundle[i]])
+    }
+
+    r, err := f.ReplayGainContext(ctx, paths)
+    if err != nil {
+      return err
+    }
+
+    for i := range bundle {
+      rg[bundle[i]] = r[i]
+    }
+    return nil
+  })
+
+  return rg, err
+}
+
+// add REPLAYGAIN_* tags to an existing file without re-encoding
+func (f *ffmpeg) WriteReplayGain(path string, rg *ReplayGain) error {
+  return f.WriteReplayGainContext(context.Background(), path, rg)
+}
+
+// add REPLAYGAIN_* tags to an existing file, aborting if ctx is done
+func (f *ffmpeg) WriteReplayGainContext(ctx context.Context, path string,
+  rg *ReplayGain) error {
+
+  style, ok := tagStyleFor(path)
+  if !ok || !replayGainSupported(style) {
+    return fmt.Errorf("replaygain tags not supported for %v", path)
+  }
+
+  return f.rewrite(ctx, path, false, func(tmp string) []string {
+    a := []string{ "-i", path, "-map", "0", "-c", "copy" }
+    if style == tagsId3 {
+      a = append(a, "-id3v2_version", "4")
+    }
+    a = append(a, replayGainArgs(style, rg)...)
+    return append(a, "-y", tmp)
+  })
+}
+
+// loudness in LUFS & sample peak (linear) from ebur128 summary
+func (f *ffmpeg) ebur128(ctx context.Context,
+  args []string) (float64, float64, error) {
+
+  a := append([]string{ "-hide_banner", "-nostats" }, args...)
+  stderr, err := f.run(ctx, nil, ioutil.Discard,
+    append(a, "-f", "null", "-"))
+  if err != nil || f.dryRun != nil {
+    return 0, 0, err
+  }
+  return parseEbur128(stderr)
+}
+
+func parseEbur128(stderr string) (float64, float64, error) {
+  i := strings.LastIndex(stderr, "Summary:")
+  if i < 0 {
+    return 0, 0, fmt.Errorf("ebur128 summary not found in ffmpeg output")
+  }
+  summary := stderr[i:]
+
+  m := regexpEbur128I.FindStringSubmatch(summary)
+  p := regexpEbur128Peak.FindStringSubmatch(summary)
+  if m == nil || p == nil {
+    return 0, 0, fmt.Errorf("ebur128 summary incomplete")
+  }
+
+  loudness, err := strconv.ParseFloat(m[1], 64)
+  if err != nil {
+    return 0, 0, err
+  }
+  peak, err := strconv.ParseFloat(p[1], 64)
+  if err != nil {
+    return 0, 0, err
+  }
+
+  // dBFS to linear amplitude (-inf is 0)
+  return loudness, math.Pow(10, peak / 20), nil
+}
+
+// replaygain tags are only written to id3v2 (TXXX) & vorbis comments
+func replayGainSupported(style tagStyle) bool {
+  return style == tagsId3 || style == tagsVorbis
+}
+
+func replayGainArgs(style tagStyle, rg *ReplayGain) []string {
+  a := []string{}
+  if rg == nil || !replayGainSupported(style) {
+    return a
+  }
+  for _, t := range rg.Tags() {
+    a = append(a, "-metadata", t[0] + "=" + t[1])
+  }
+  return a
+}
@@ -0,0 +1,51 @@
+package ffmpeg
+
+import (
+  "math"
+  "testing"
+)
+
+func TestParseEbur128(t *testing.T) {
+  stderr := `[Parsed_ebur128_0 @ 0x5581] t: 4.1 TARGET:-23 LUFS M: -18.2 S: -19.0 I: -19.1 LUFS LRA: 0.0 LU
+[Parsed_ebur128_0 @ 0x5581] Summary:
+
+  Integrated loudness:
+    I:         -14.0 LUFS
+    Threshold: -24.6 LUFS
+
+  Loudness range:
+    LRA:         4.2 LU
+    Threshold:  -34.6 LUFS
+    LRA low:    -17.4 LUFS
+    LRA high:   -13.2 LUFS
+
+  Sample peak:
+    Peak:        -6.0 dBFS
+`
+
+  loudness, peak, err := parseEbur128(stderr)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if loudness != -14 {
+    t.Errorf("Expected -14, got %v", loudness)
+  }
+  if math.Abs(peak - 0.501187) > 0.000001 {
+    t.Errorf("Expected 0.501187, got %v", peak)
+  }
+
+  rg := &ReplayGain{ TrackGain: ReplayGainReference - loudness,
+    TrackPeak: peak, AlbumGain: -3.456, AlbumPeak: 1 }
+  tags := rg.Tags()
+  exp := []string{ "-4.00 dB", "0.501187", "-3.46 dB", "1.000000" }
+  for i := range exp {
+    if tags[i][1] != exp[i] {
+      t.Errorf("Expected %v, got %v", exp[i], tags[i][1])
+    }
+  }
+
+  _, _, err = parseEbur128("Conversion failed!")
+  if err == nil {
+    t.Errorf("Expected error when summary missing")
+  }
+}
@@ -0,0 +1,135 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "context"
+  "strconv"
+  "strings"
+)
+
+// noise shaping applied when reducing bit depth
+type Dither string
+
+const (
+  DitherTriangular Dither = "triangular"
+  DitherShibata Dither = "shibata"
+  DitherNone Dither = "none"
+)
+
+// sample rate & bit depth conversion
+type ResampleConfig struct {
+  // target sample rate in Hz, 0 keeps the source rate
+  SampleRate int
+  // target bits per sample, flac 16 or 24 & wav 16, 24 or 32. 0 keeps the
+  // encoder default
+  BitDepth int
+  // default triangular
+  Dither Dither
+  // allow a SampleRate above the source rate
+  AllowUpsample bool
+}
+
+func (r *ResampleConfig) validate(codec Codec) error {
+  if r.SampleRate < 0 {
+    return fmt.Errorf("sample rate must not be negative")
+  }
+
+  switch r.Dither {
+  case "", DitherTriangular, DitherShibata, DitherNone:
+  default:
+    return fmt.Errorf("unknown dither %q", r.Dither)
+  }
+
+  switch {
+  case r.BitDepth == 0:
+  case codec == CodecFlac && (r.BitDepth == 16 || r.BitDepth == 24):
+  case codec == CodecWav && (r.BitDepth == 16 || r.BitDepth == 24 ||
+    r.BitDepth == 32):
+  case codec != CodecFlac && codec != CodecWav:
+    return fmt.Errorf("bit depth only applies to flac & wav")
+  default:
+    return fmt.Errorf("unsupported %v bit depth %d", codec, r.BitDepth)
+  }
+
+  return nil
+}
+
+// aresample filter converting from source rate, "" if nothing to convert.
+// soxr is used for rate conversion when ffmpeg is built with libsoxr
+func (r *ResampleConfig) filter(source int, soxr bool) (string, error) {
+  if r.SampleRate > source && !r.AllowUpsample {
+    return "", fmt.Errorf("refusing to upsample from %d to %d Hz",
+      source, r.SampleRate)
+  }
+
+  a := []string{}
+  if r.SampleRate > 0 && r.SampleRate != source {
+    a = append(a, "osr=" + strconv.Itoa(r.SampleRate))
+    if soxr {
+      a = append(a, "resampler=soxr", "precision=28")
+    }
+  }
+
+  if r.BitDepth > 0 {
+    a = append(a, "osf=" + sampleFmt(r.BitDepth))
+    // 24 bits are carried in s32, scale dither to the 24th bit rather than
+    // the 32nd so it survives the encoder dropping the low byte
+    if r.BitDepth == 24 {
+      a = append(a, "osb=24")
+    }
+
+    switch r.Dither {
+    case "":
+      a = append(a, "dither_method=" + string(DitherTriangular))
+    case DitherNone:
+    default:
+      a = append(a, "dither_method=" + string(r.Dither))
+    }
+  }
+
+  if len(a) == 0 {
+    return "", nil
+  }
+  return "aresample=" + strings.Join(a, ":"), nil
+}
+
+// packed sample format holding bit depth
+func sampleFmt(depth int) string {
+  if depth == 16 {
+    return "s16"
+  }
+  return "s32"
+}
+
+// encoder arguments producing bit depth
+func (r *ResampleConfig) codecArgs(codec Codec, q Quality) ([]string, error) {
+  a := codecArgs(codec, q)
+  if r.BitDepth == 0 {
+    return a, nil
+  }
+  if q.Copy {
+    return a, fmt.Errorf("bit depth conversion requires re-encoding, not copy")
+  }
+
+  if codec == CodecWav {
+    a[1] = fmt.Sprintf("pcm_s%dle", r.BitDepth)
+    return a, nil
+  }
+
+  a = append(a, "-sample_fmt", sampleFmt(r.BitDepth))
+  if r.BitDepth == 24 {
+    a = append(a, "-bits_per_raw_sample", "24")
+  }
+  return a, nil
+}
+
+// resample filter converting from the sample rate out of prior filters
+func (f *ffmpeg) resampleFilter(ctx context.Context, r *ResampleConfig,
+  rate int) (string, error) {
+
+  soxr := false
+  if c := f.capabilities(ctx); c != nil {
+    soxr = c.Enabled("libsoxr")
+  }
+  return r.filter(rate, soxr)
+}
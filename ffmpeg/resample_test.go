@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestResampleFilter(t *testing.T) {
+  tests := []struct {
+    r ResampleConfig
+    soxr bool
+    filter string
+    err bool
+  }{
+    { ResampleConfig{ SampleRate: 44100, BitDepth: 16 }, true,
+      "aresample=osr=44100:resampler=soxr:precision=28:osf=s16:" +
+      "dither_method=triangular", false },
+    { ResampleConfig{ SampleRate: 48000, Dither: DitherShibata }, false,
+      "aresample=osr=48000", false },
+    { ResampleConfig{ BitDepth: 24, Dither: DitherNone }, false,
+      "aresample=osf=s32:osb=24", false },
+    { ResampleConfig{ BitDepth: 24 }, false,
+      "aresample=osf=s32:osb=24:dither_method=triangular", false },
+    { ResampleConfig{ SampleRate: 96000 }, false, "", false },
+    { ResampleConfig{ SampleRate: 192000 }, false, "", true },
+    { ResampleConfig{ SampleRate: 192000, AllowUpsample: true }, false,
+      "aresample=osr=192000", false },
+  }
+
+  for i := range tests {
+    f, err := tests[i].r.filter(96000, tests[i].soxr)
+    if (err != nil) != tests[i].err {
+      t.Errorf("Expected error %v, got %v", tests[i].err, err)
+    }
+    if f != tests[i].filter {
+      t.Errorf("Expected %v, got %v", tests[i].filter, f)
+    }
+  }
+}
+
+func TestResampleArgs(t *testing.T) {
+  tests := []struct {
+    c TranscodeConfig
+    args string
+    err bool
+  }{
+    { TranscodeConfig{ Codec: CodecFlac,
+      Resample: &ResampleConfig{ BitDepth: 24 } },
+      "-c:a flac -sample_fmt s32 -bits_per_raw_sample 24", false },
+    { TranscodeConfig{ Codec: CodecWav,
+      Resample: &ResampleConfig{ BitDepth: 24 } }, "-c:a pcm_s24le", false },
+    { TranscodeConfig{ Codec: CodecMp3,
+      Resample: &ResampleConfig{ BitDepth: 16 } }, "", true },
+    { TranscodeConfig{ Codec: CodecFlac,
+      Resample: &ResampleConfig{ BitDepth: 32 } }, "", true },
+    { TranscodeConfig{ Codec: CodecFlac, Quality: Quality{ Copy: true },
+      Resample: &ResampleConfig{ BitDepth: 16 } }, "", true },
+    { TranscodeConfig{ Codec: CodecFlac,
+      Resample: &ResampleConfig{ Dither: "noise" } }, "", true },
+  }
+
+  for i := range tests {
+    c := tests[i].c
+    c.Input, c.Output = "in.flac", "out"
+
+    a, err := transcodeArgs(&c)
+    if (err != nil) != tests[i].err {
+      t.Errorf("Expected error %v, got %v", tests[i].err, err)
+    }
+    if err == nil && !strings.Contains(strings.Join(a, " "), tests[i].args) {
+      t.Errorf("Expected %v in %v", tests[i].args, strings.Join(a, " "))
+    }
+  }
+}
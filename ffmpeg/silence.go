@@ -0,0 +1,132 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "time"
+  "regexp"
+  "context"
+  "strconv"
+  "io/ioutil"
+)
+
+// silencedetect settings
+type SilenceConfig struct {
+  // noise level in dB below which audio is considered silent, e.g. -60
+  Threshold float64
+  // shortest silence reported
+  MinDuration time.Duration
+}
+
+// silent interval within a file
+type Silence struct {
+  Start, End, Duration time.Duration
+}
+
+// -60dB for at least 2 seconds
+var DefaultSilence = SilenceConfig{ Threshold: -60, MinDuration: 2 * time.Second }
+
+var regexpSilence = regexp.MustCompile(
+  `silence_(start|end): ([-+\d.eE]+)(?: \| silence_duration: ([-+\d.eE]+))?`)
+
+// find silent intervals within input
+func (f *ffmpeg) DetectSilence(input string,
+  c SilenceConfig) ([]Silence, error) {
+
+  return f.DetectSilenceContext(context.Background(), input, c)
+}
+
+// find silent intervals within input, aborting if ctx is done
+func (f *ffmpeg) DetectSilenceContext(ctx context.Context, input string,
+  c SilenceConfig) ([]Silence, error) {
+
+  if c.Threshold >= 0 || c.MinDuration <= 0 {
+    return nil, fmt.Errorf("silence threshold must be negative dB and " +
+      "minimum duration positive")
+  }
+
+  a := []string{ "-hide_banner", "-nostats", "-i", input, "-map", "0:a",
+    "-af", fmt.Sprintf("silencedetect=noise=%vdB:d=%v", c.Threshold,
+    c.MinDuration.Seconds()), "-f", "null", "-" }
+
+  stderr, err := f.run(ctx, nil, ioutil.Discard, a)
+  if err != nil {
+    return nil, err
+  }
+
+  s := parseSilence(stderr)
+
+  // older ffmpeg does not report silence running to end of file
+  if len(s) > 0 && s[len(s)-1].End == 0 {
+    d := time.Duration(probeDuration(ctx, input) * float64(time.Second))
+    last := &s[len(s)-1]
+    if d > last.Start {
+      last.End, last.Duration = d, d - last.Start
+    } else {
+      s = s[:len(s)-1]
+    }
+  }
+
+  return s, nil
+}
+
+// pair silence_start & silence_end lines from silencedetect output
+func parseSilence(stderr string) []Silence {
+  s := []Silence{}
+
+  for _, m := range regexpSilence.FindAllStringSubmatch(stderr, -1) {
+    secs, err := strconv.ParseFloat(m[2], 64)
+    if err != nil {
+      continue
+    }
+    t := seconds(secs)
+
+    if m[1] == "start" {
+      if t < 0 {
+        t = 0
+      }
+      s = append(s, Silence{ Start: t })
+      continue
+    }
+
+    if len(s) == 0 || s[len(s)-1].End != 0 {
+      continue
+    }
+    s[len(s)-1].End = t
+    s[len(s)-1].Duration = t - s[len(s)-1].Start
+  }
+
+  return s
+}
+
+// convert float seconds to time.Duration
+func seconds(s float64) time.Duration {
+  return time.Duration(s * float64(time.Second))
+}
+
+// atrim filter removing silence touching start or end of a file of length d.
+// empty if nothing to trim
+func trimFilter(s []Silence, d time.Duration) string {
+  // tolerate rounding of reported timestamps
+  const slop = 50 * time.Millisecond
+
+  start, end := time.Duration(0), d
+  for i := range s {
+    if s[i].Start <= slop {
+      start = s[i].End
+    }
+    if d > 0 && s[i].End >= d - slop && s[i].Start > start {
+      end = s[i].Start
+    }
+  }
+
+  // nothing to trim or entirely silent
+  if (start == 0 && end == d) || start >= end {
+    return ""
+  }
+
+  f := fmt.Sprintf("atrim=start=%v", start.Seconds())
+  if end != d {
+    f += fmt.Sprintf(":end=%v", end.Seconds())
+  }
+  return f + ",asetpts=PTS-STARTPTS"
+}
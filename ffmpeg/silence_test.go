@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+  "time"
+  "testing"
+)
+
+func TestParseSilence(t *testing.T) {
+  stderr := `[silencedetect @ 0x55] silence_start: -0.00133333
+[silencedetect @ 0x55] silence_end: 31.5 | silence_duration: 31.5013
+size=N/A time=00:04:10.00 bitrate=N/A speed= 412x
+[silencedetect @ 0x55] silence_start: 240.25
+[silencedetect @ 0x55] silence_end: 250 | silence_duration: 9.75
+`
+
+  s := parseSilence(stderr)
+  exp := []Silence{
+    { Start: 0, End: 31500 * time.Millisecond, Duration: 31500 * time.Millisecond },
+    { Start: 240250 * time.Millisecond, End: 250 * time.Second,
+      Duration: 9750 * time.Millisecond },
+  }
+
+  if len(s) != len(exp) {
+    t.Fatalf("Expected %v, got %v", exp, s)
+  }
+  for i := range exp {
+    if s[i] != exp[i] {
+      t.Errorf("Expected %+v, got %+v", exp[i], s[i])
+    }
+  }
+
+  // %.6g timestamps use an exponent for tiny values
+  s = parseSilence("[silencedetect @ 0x55] silence_start: 2.26757e-05\n" +
+    "[silencedetect @ 0x55] silence_end: 1.5 | silence_duration: 1.49998\n")
+  if len(s) != 1 || s[0].Start != seconds(2.26757e-05) ||
+    s[0].End != 1500 * time.Millisecond {
+    t.Errorf("Expected silence from 22.675µs to 1.5s, got %+v", s)
+  }
+}
+
+func TestTrimFilter(t *testing.T) {
+  d := 250 * time.Second
+  lead := Silence{ Start: 0, End: 30 * time.Second }
+  mid := Silence{ Start: 100 * time.Second, End: 110 * time.Second }
+  trail := Silence{ Start: 240 * time.Second, End: d }
+
+  tests := []struct {
+    silence []Silence
+    filter string
+  }{
+    { []Silence{ lead, mid, trail }, "atrim=start=30:end=240,asetpts=PTS-STARTPTS" },
+    { []Silence{ lead }, "atrim=start=30,asetpts=PTS-STARTPTS" },
+    { []Silence{ mid, trail }, "atrim=start=0:end=240,asetpts=PTS-STARTPTS" },
+    { []Silence{ mid }, "" },
+    { []Silence{ { Start: 0, End: d } }, "" },
+  }
+
+  for i := range tests {
+    f := trimFilter(tests[i].silence, d)
+    if f != tests[i].filter {
+      t.Errorf("Expected %v, got %v", tests[i].filter, f)
+    }
+  }
+}
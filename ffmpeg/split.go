@@ -0,0 +1,164 @@
+package ffmpeg
+
+import (
+  "os"
+  "fmt"
+  "time"
+  "errors"
+  "context"
+  "strconv"
+  "strings"
+  "path/filepath"
+
+  "github.com/jamlib/libaudio/cue"
+)
+
+type SplitConfig struct {
+  // path to cue sheet, FILE entries are relative to its directory
+  Cue string
+  // directory tracks are written to
+  OutputDir string
+  // output file name (without extension) for a track, "01 Title" if nil
+  Name func(m Metadata) string `json:"-"`
+  // codec, quality & artwork for every track. Input, Output, Duration & Meta
+  // (other than Artwork) are set per track
+  Transcode TranscodeConfig
+}
+
+// split single file images described by a cue sheet into tracks, returns
+// output paths in track order
+func (f *ffmpeg) SplitCue(c *SplitConfig) ([]string, error) {
+  return f.SplitCueContext(context.Background(), c)
+}
+
+// split cue sheet into tracks, aborting if ctx is done
+func (f *ffmpeg) SplitCueContext(ctx context.Context,
+  c *SplitConfig) ([]string, error) {
+
+  outputs := []string{}
+
+  // silence & loudness would be measured over the entire image
+  if c.Transcode.TrimSilence != nil || c.Transcode.Normalize != nil {
+    return outputs, errors.New("TrimSilence & Normalize not supported " +
+      "when splitting")
+  }
+
+  codec, q, err := c.Transcode.codec()
+  if err != nil {
+    return outputs, err
+  }
+  if q.Copy {
+    return outputs, errors.New("splitting requires re-encoding, not copy")
+  }
+
+  sheet, err := cue.ParseFile(c.Cue)
+  if err != nil {
+    return outputs, err
+  }
+
+  name := c.Name
+  if name == nil {
+    name = trackName
+  }
+
+  dir := filepath.Dir(c.Cue)
+  rates := map[string]int{}
+
+  for i, tr := range sheet.Tracks {
+    if tr.Type != "AUDIO" {
+      continue
+    }
+
+    input := filepath.Join(dir, tr.File)
+    rate, ok := rates[input]
+    if !ok {
+      rate, err = sampleRate(ctx, input)
+      if err != nil {
+        return outputs, err
+      }
+      rates[input] = rate
+    }
+
+    start := tr.Start()
+    end, bounded := sheet.End(i)
+    seek, trim := trackTrim(start, end, bounded, rate)
+
+    t := c.Transcode
+    t.Codec, t.Quality, t.Preset = codec, q, ""
+    t.Input, t.start = input, seek
+    t.Meta = cueMetadata(sheet, tr, c.Transcode.Meta.Artwork)
+    t.Output = filepath.Join(c.OutputDir, sanitize(name(t.Meta)) + "." +
+      codecs[codec].ext)
+    t.Duration = 0
+    if bounded {
+      t.Duration = (end - start).Duration().Seconds()
+    }
+
+    _, _, err = f.transcode(ctx, &t, trim, "asetpts=PTS-STARTPTS")
+    if err != nil {
+      return outputs, err
+    }
+    outputs = append(outputs, t.Output)
+  }
+
+  return outputs, nil
+}
+
+// input seek & atrim filter for a track. seeks to a whole second before
+// start so only the track is decoded, then trims by sample relative to it
+// for exact track boundaries
+func trackTrim(start, end cue.Time, bounded bool,
+  rate int) (time.Duration, string) {
+
+  secs := start.Samples(rate) / int64(rate) - 1
+  if secs < 0 {
+    secs = 0
+  }
+  offset := secs * int64(rate)
+
+  trim := fmt.Sprintf("atrim=start_sample=%d", start.Samples(rate) - offset)
+  if bounded {
+    trim += fmt.Sprintf(":end_sample=%d", end.Samples(rate) - offset)
+  }
+  return time.Duration(secs) * time.Second, trim
+}
+
+// metadata for track from cue sheet, track performer overrides sheet
+func cueMetadata(s *cue.Sheet, t *cue.Track, artwork string) Metadata {
+  m := Metadata{ Artist: s.Performer, Album: s.Title, Disc: s.Disc,
+    Track: strconv.Itoa(t.Number), Title: t.Title, Date: s.Date,
+    Artwork: artwork, Genre: s.Genre, Composer: t.Songwriter }
+
+  if len(t.Performer) > 0 && t.Performer != s.Performer {
+    m.Artist, m.AlbumArtist = t.Performer, s.Performer
+  }
+  return m
+}
+
+// default track file name: zero padded track number & title
+func trackName(m Metadata) string {
+  n, _ := strconv.Atoi(m.Track)
+  if len(m.Title) == 0 {
+    return fmt.Sprintf("%02d", n)
+  }
+  return fmt.Sprintf("%02d %s", n, m.Title)
+}
+
+// remove path separators from file name
+func sanitize(name string) string {
+  return strings.Map(func(r rune) rune {
+    if r == '/' || r == os.PathSeparator {
+      return '-'
+    }
+    return r
+  }, name)
+}
+
+// sample rate of first audio stream
+func sampleRate(ctx context.Context, input string) (int, error) {
+  s, err := audioStream(ctx, input)
+  if err != nil {
+    return 0, err
+  }
+  return strconv.Atoi(s.SampleRate)
+}
@@ -0,0 +1,34 @@
+package ffmpeg
+
+import (
+  "time"
+  "testing"
+
+  "github.com/jamlib/libaudio/cue"
+)
+
+func TestTrackTrim(t *testing.T) {
+  tests := []struct {
+    start, end cue.Time
+    bounded bool
+    seek time.Duration
+    trim string
+  }{
+    // 00:00:00 - 03:10:40
+    { 0, 14290, true, 0, "atrim=start_sample=0:end_sample=8402520" },
+    // 00:01:37 to end, within first 2 seconds
+    { 112, 0, false, 0, "atrim=start_sample=65856" },
+    // 03:10:40 - 07:02:00
+    { 14290, 31650, true, 189 * time.Second,
+      "atrim=start_sample=67620:end_sample=10275300" },
+  }
+
+  for i := range tests {
+    seek, trim := trackTrim(tests[i].start, tests[i].end, tests[i].bounded,
+      44100)
+    if seek != tests[i].seek || trim != tests[i].trim {
+      t.Errorf("Expected %v %v, got %v %v", tests[i].seek, tests[i].trim,
+        seek, trim)
+    }
+  }
+}
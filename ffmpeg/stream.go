@@ -0,0 +1,39 @@
+package ffmpeg
+
+import (
+  "io"
+  "errors"
+  "context"
+)
+
+// transcode audio read from r, writing encoded output to w as it is
+// produced. c.Input & c.Output are ignored. TrimSilence, Normalize,
+// Progress, Resample & Channels are not supported since they need to probe or read
+// the input twice, or share stdout
+func (f *ffmpeg) TranscodeStream(r io.Reader, w io.Writer,
+  c *TranscodeConfig) error {
+
+  return f.TranscodeStreamContext(context.Background(), r, w, c)
+}
+
+// streaming transcode, aborting if ctx is done
+func (f *ffmpeg) TranscodeStreamContext(ctx context.Context, r io.Reader,
+  w io.Writer, c *TranscodeConfig) error {
+
+  if c.TrimSilence != nil || c.Normalize != nil || c.Progress != nil ||
+    c.Resample != nil || c.Channels != nil {
+    return errors.New("TrimSilence, Normalize, Progress, Resample & " +
+      "Channels not supported when streaming")
+  }
+
+  t := *c
+  t.Input, t.Output = "pipe:0", "pipe:1"
+
+  a, err := transcodeArgs(&t)
+  if err != nil {
+    return err
+  }
+
+  _, err = f.run(ctx, r, w, a)
+  return err
+}
@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "context"
+)
+
+type TagOptions struct {
+  // keep existing tags for empty Metadata fields instead of removing them,
+  // and keep existing artwork unless Metadata.Artwork is set
+  Merge bool
+  // keep the file's modification time (file mode is always kept)
+  PreserveModTime bool
+}
+
+// replace or merge tags & artwork of an existing file without re-encoding.
+// written to a temp file in the same directory then renamed over path
+func (f *ffmpeg) WriteTags(path string, meta Metadata, opts *TagOptions) error {
+  return f.WriteTagsContext(context.Background(), path, meta, opts)
+}
+
+// retag existing file, aborting if ctx is done
+func (f *ffmpeg) WriteTagsContext(ctx context.Context, path string,
+  meta Metadata, opts *TagOptions) error {
+
+  if opts == nil {
+    opts = &TagOptions{}
+  }
+
+  codec, ok := codecFor(path)
+  if !ok {
+    return fmt.Errorf("unsupported file type for tagging: %v", path)
+  }
+  if err := meta.supported(codecs[codec].tags); err != nil {
+    return err
+  }
+
+  return f.rewrite(ctx, path, opts.PreserveModTime, func(tmp string) []string {
+    return writeTagsArgs(path, tmp, codec, meta, opts)
+  })
+}
+
+// build ffmpeg arguments to stream copy path to tmp with new tags
+func writeTagsArgs(path, tmp string, codec Codec, meta Metadata,
+  opts *TagOptions) []string {
+
+  info := codecs[codec]
+  artwork := info.artwork && len(meta.Artwork) > 0
+
+  a := []string{ "-i", path }
+  if artwork {
+    a = append(a, "-i", meta.Artwork)
+  }
+
+  a = append(a, "-map", "0:a")
+  // existing pictures are kept when merging without new artwork
+  if opts.Merge && !artwork {
+    a = append(a, "-map", "0:v?")
+  }
+  a = append(a, "-c", "copy")
+
+  if opts.Merge {
+    a = append(a, mergeMetadataArgs(info.tags, meta)...)
+  } else {
+    a = append(a, "-map_metadata", "-1")
+    a = append(a, metadataArgs(info.tags, meta)...)
+  }
+
+  if artwork {
+    a = append(a, artworkArgs(info.tags, 1)...)
+  }
+
+  return append(a, "-f", info.format, "-y", tmp)
+}
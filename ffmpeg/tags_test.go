@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+  "os"
+  "time"
+  "context"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestWriteTagsArgs(t *testing.T) {
+  meta := Metadata{ Album: "Album", Artwork: "cover.jpg" }
+
+  tests := []struct {
+    meta Metadata
+    opts *TagOptions
+    args string
+  }{
+    { meta, &TagOptions{},
+      "-i in.flac -i cover.jpg -map 0:a -c copy -map_metadata -1 " +
+      "-metadata ARTIST= -metadata ALBUM=Album -metadata DISCNUMBER= " +
+      "-metadata TRACKNUMBER= -metadata TITLE= -metadata DATE= " +
+      "-map 1:v -c:v copy -disposition:v attached_pic " +
+      "-metadata:s:v comment=Cover (front) -f flac -y tmp.flac" },
+    { Metadata{ Album: "Album" }, &TagOptions{ Merge: true },
+      "-i in.flac -map 0:a -map 0:v? -c copy -metadata ALBUM=Album " +
+      "-f flac -y tmp.flac" },
+  }
+
+  for i := range tests {
+    a := writeTagsArgs("in.flac", "tmp.flac", CodecFlac, tests[i].meta,
+      tests[i].opts)
+    if strings.Join(a, " ") != tests[i].args {
+      t.Errorf("Expected %v, got %v", tests[i].args, strings.Join(a, " "))
+    }
+  }
+}
+
+func TestRewrite(t *testing.T) {
+  dir, err := ioutil.TempDir("", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  path := filepath.Join(dir, "track.mp3")
+  src := filepath.Join(dir, "new")
+  _ = ioutil.WriteFile(path, []byte("old"), 0640)
+  _ = ioutil.WriteFile(src, []byte("new"), 0600)
+
+  mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+  _ = os.Chtimes(path, mtime, mtime)
+
+  // cp stands in for ffmpeg
+  f := &ffmpeg{ Bin: "cp" }
+  err = f.rewrite(context.Background(), path, true, func(tmp string) []string {
+    return []string{ src, tmp }
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  b, _ := ioutil.ReadFile(path)
+  info, _ := os.Stat(path)
+  if string(b) != "new" || info.Mode() != 0640 || !info.ModTime().Equal(mtime) {
+    t.Errorf("Expected new contents, mode 0640 & mtime %v, got %q, %v, %v",
+      mtime, b, info.Mode(), info.ModTime())
+  }
+
+  // failure leaves original & no temp file behind
+  f.Bin = "false"
+  err = f.rewrite(context.Background(), path, false, func(tmp string) []string {
+    return []string{}
+  })
+  files, _ := ioutil.ReadDir(dir)
+  if err == nil || len(files) != 2 {
+    t.Errorf("Expected error & 2 files, got %v & %v files", err, len(files))
+  }
+}
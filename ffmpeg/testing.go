@@ -3,9 +3,14 @@ package ffmpeg
 import (
   "io"
   "os"
+  "time"
+  "bytes"
+  "context"
   "io/ioutil"
+  "path/filepath"
   "encoding/json"
 
+  "github.com/jamlib/libaudio/cue"
   "github.com/jamlib/libaudio/fsutil"
 )
 
@@ -13,6 +18,37 @@ type MockFfmpeg struct {
   Embedded string
 }
 
+// reports every encoder, muxer & filter this package uses
+func (m *MockFfmpeg) Capabilities() (*Capabilities, error) {
+  c := &Capabilities{ Version: "mock", Configuration: []string{},
+    Encoders: map[string]bool{ "libfdk_aac": true, "pcm_s16le": true,
+      "pcm_f32le": true, "pcm_s8": true, "pcm_s24le": true,
+      "pcm_s32le": true, "png": true, "mjpeg": true },
+    Muxers: map[string]bool{ "null": true, "image2": true, "s16le": true,
+      "f32le": true, "md5": true },
+    Filters: map[string]bool{} }
+
+  for _, info := range codecs {
+    c.Encoders[info.encoder] = true
+    c.Muxers[info.format] = true
+  }
+  for _, n := range []string{ "loudnorm", "ebur128", "silencedetect", "atrim",
+    "asetpts", "concat", "aresample", "pan", "afade", "acrossfade",
+    "showwavespic", "showspectrumpic", "scale", "crop", "pad" } {
+    c.Filters[n] = true
+  }
+  return c, nil
+}
+
+func (m *MockFfmpeg) CapabilitiesContext(ctx context.Context) (*Capabilities,
+  error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.Capabilities()
+}
+
 func (m *MockFfmpeg) OptimizeAlbumArt(s, d string) (string, error) {
   // temp file for optimizing
   tmp, err := ioutil.TempFile("", "")
@@ -45,8 +81,49 @@ func (m *MockFfmpeg) OptimizeAlbumArt(s, d string) (string, error) {
   return "", nil
 }
 
+func (m *MockFfmpeg) OptimizeAlbumArtContext(ctx context.Context,
+  s, d string) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.OptimizeAlbumArt(s, d)
+}
+
+func (m *MockFfmpeg) OptimizeArtwork(input, output string,
+  c *ArtworkConfig) (string, error) {
+
+  return m.OptimizeAlbumArt(input, output)
+}
+
+func (m *MockFfmpeg) OptimizeArtworkContext(ctx context.Context, input,
+  output string, c *ArtworkConfig) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.OptimizeArtwork(input, output, c)
+}
+
+func (m *MockFfmpeg) ExtractAlbumArt(input, output string) (*Picture, error) {
+  err := ioutil.WriteFile(output, []byte(m.Embedded), 0644)
+  if err != nil {
+    return nil, err
+  }
+  return &Picture{ Codec: "mjpeg", Type: "Cover (front)" }, nil
+}
+
+func (m *MockFfmpeg) ExtractAlbumArtContext(ctx context.Context,
+  input, output string) (*Picture, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.ExtractAlbumArt(input, output)
+}
+
 func (m *MockFfmpeg) Exec(args ...string) (string, error) {
-  // hook on extract audio
+  // hook on extract audio (kept for callers extracting art via Exec)
   if len(args) == 4 {
     err := ioutil.WriteFile(args[3], []byte(m.Embedded), 0644)
     if err != nil {
@@ -56,6 +133,15 @@ func (m *MockFfmpeg) Exec(args ...string) (string, error) {
   return "", nil
 }
 
+func (m *MockFfmpeg) ExecContext(ctx context.Context,
+  args ...string) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.Exec(args...)
+}
+
 func (m *MockFfmpeg) ToMp3(c *Mp3Config) (string, error) {
   b, err := json.Marshal(c)
   if err != nil {
@@ -69,3 +155,368 @@ func (m *MockFfmpeg) ToMp3(c *Mp3Config) (string, error) {
 
   return c.Output, nil
 }
+
+func (m *MockFfmpeg) ToMp3Context(ctx context.Context,
+  c *Mp3Config) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.ToMp3(c)
+}
+
+func (m *MockFfmpeg) Transcode(c *TranscodeConfig) (string, error) {
+  b, err := json.Marshal(c)
+  if err != nil {
+    return "", err
+  }
+
+  err = ioutil.WriteFile(c.Output, b, 0644)
+  if err != nil {
+    return "", err
+  }
+
+  return c.Output, nil
+}
+
+func (m *MockFfmpeg) TranscodeContext(ctx context.Context,
+  c *TranscodeConfig) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.Transcode(c)
+}
+
+func (m *MockFfmpeg) MeasureLoudness(input string,
+  target Loudness) (*LoudnessStats, error) {
+
+  return &LoudnessStats{ InputI: target.Integrated,
+    InputTP: target.TruePeak, InputLRA: target.Range,
+    NormalizationType: "linear" }, nil
+}
+
+func (m *MockFfmpeg) MeasureLoudnessContext(ctx context.Context, input string,
+  target Loudness) (*LoudnessStats, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.MeasureLoudness(input, target)
+}
+
+func (m *MockFfmpeg) Normalize(c *TranscodeConfig) (*LoudnessStats, error) {
+  if c.Normalize == nil {
+    t := DefaultLoudness
+    c.Normalize = &t
+  }
+
+  _, err := m.Transcode(c)
+  if err != nil {
+    return nil, err
+  }
+  return m.MeasureLoudness(c.Input, *c.Normalize)
+}
+
+func (m *MockFfmpeg) NormalizeContext(ctx context.Context,
+  c *TranscodeConfig) (*LoudnessStats, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.Normalize(c)
+}
+
+func (m *MockFfmpeg) ReplayGain(files []string) ([]*ReplayGain, error) {
+  rg := make([]*ReplayGain, len(files))
+  for i := range files {
+    rg[i] = &ReplayGain{ TrackPeak: 1, AlbumPeak: 1 }
+  }
+  return rg, nil
+}
+
+func (m *MockFfmpeg) ReplayGainContext(ctx context.Context,
+  files []string) ([]*ReplayGain, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.ReplayGain(files)
+}
+
+func (m *MockFfmpeg) ReplayGainBundles(dir string,
+  files []string) ([]*ReplayGain, error) {
+
+  return m.ReplayGain(files)
+}
+
+func (m *MockFfmpeg) ReplayGainBundlesContext(ctx context.Context, dir string,
+  files []string) ([]*ReplayGain, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.ReplayGain(files)
+}
+
+func (m *MockFfmpeg) WriteReplayGain(path string, rg *ReplayGain) error {
+  _, err := os.Stat(path)
+  return err
+}
+
+func (m *MockFfmpeg) WriteReplayGainContext(ctx context.Context, path string,
+  rg *ReplayGain) error {
+
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+  return m.WriteReplayGain(path, rg)
+}
+
+func (m *MockFfmpeg) DetectSilence(input string,
+  c SilenceConfig) ([]Silence, error) {
+
+  return []Silence{}, nil
+}
+
+func (m *MockFfmpeg) DetectSilenceContext(ctx context.Context, input string,
+  c SilenceConfig) ([]Silence, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.DetectSilence(input, c)
+}
+
+func (m *MockFfmpeg) SplitCue(c *SplitConfig) ([]string, error) {
+  sheet, err := cue.ParseFile(c.Cue)
+  if err != nil {
+    return []string{}, err
+  }
+
+  name := c.Name
+  if name == nil {
+    name = trackName
+  }
+
+  outputs := []string{}
+  for _, tr := range sheet.Tracks {
+    t := c.Transcode
+    t.Input = filepath.Join(filepath.Dir(c.Cue), tr.File)
+    t.Meta = cueMetadata(sheet, tr, c.Transcode.Meta.Artwork)
+    t.Output = filepath.Join(c.OutputDir, sanitize(name(t.Meta)))
+
+    o, err := m.Transcode(&t)
+    if err != nil {
+      return outputs, err
+    }
+    outputs = append(outputs, o)
+  }
+  return outputs, nil
+}
+
+func (m *MockFfmpeg) SplitCueContext(ctx context.Context,
+  c *SplitConfig) ([]string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.SplitCue(c)
+}
+
+func (m *MockFfmpeg) Concat(inputs []string, output string,
+  c *ConcatConfig) (string, error) {
+
+  // joined contents of inputs
+  var b []byte
+  for i := range inputs {
+    in, err := ioutil.ReadFile(inputs[i])
+    if err != nil {
+      return "", err
+    }
+    b = append(b, in...)
+  }
+
+  err := ioutil.WriteFile(output, b, 0644)
+  if err != nil {
+    return "", err
+  }
+  return output, nil
+}
+
+func (m *MockFfmpeg) ConcatContext(ctx context.Context, inputs []string,
+  output string, c *ConcatConfig) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.Concat(inputs, output, c)
+}
+
+func (m *MockFfmpeg) Waveform(input, output string, c *WaveformConfig) error {
+  return ioutil.WriteFile(output, []byte(input), 0644)
+}
+
+func (m *MockFfmpeg) WaveformContext(ctx context.Context, input, output string,
+  c *WaveformConfig) error {
+
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+  return m.Waveform(input, output, c)
+}
+
+func (m *MockFfmpeg) Spectrogram(input, output string,
+  c *SpectrogramConfig) error {
+
+  return ioutil.WriteFile(output, []byte(input), 0644)
+}
+
+func (m *MockFfmpeg) SpectrogramContext(ctx context.Context, input,
+  output string, c *SpectrogramConfig) error {
+
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+  return m.Spectrogram(input, output, c)
+}
+
+func (m *MockFfmpeg) Peaks(input string, n int) ([]float64, error) {
+  return make([]float64, n), nil
+}
+
+func (m *MockFfmpeg) PeaksContext(ctx context.Context, input string,
+  n int) ([]float64, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.Peaks(input, n)
+}
+
+func (m *MockFfmpeg) TranscodeStream(r io.Reader, w io.Writer,
+  c *TranscodeConfig) error {
+
+  _, err := io.Copy(w, r)
+  return err
+}
+
+func (m *MockFfmpeg) TranscodeStreamContext(ctx context.Context, r io.Reader,
+  w io.Writer, c *TranscodeConfig) error {
+
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+  return m.TranscodeStream(r, w, c)
+}
+
+func (m *MockFfmpeg) WriteTags(path string, meta Metadata,
+  opts *TagOptions) error {
+
+  _, err := os.Stat(path)
+  if err != nil {
+    return err
+  }
+
+  b, err := json.Marshal(meta)
+  if err != nil {
+    return err
+  }
+  return ioutil.WriteFile(path, b, 0644)
+}
+
+func (m *MockFfmpeg) WriteTagsContext(ctx context.Context, path string,
+  meta Metadata, opts *TagOptions) error {
+
+  if err := ctx.Err(); err != nil {
+    return err
+  }
+  return m.WriteTags(path, meta, opts)
+}
+
+// input is read as raw samples of the configured format, 44100 Hz stereo
+func (m *MockFfmpeg) Decode(input string, c *DecodeConfig) (*Decoder,
+  error) {
+
+  if c == nil {
+    c = &DecodeConfig{}
+  }
+
+  b, err := ioutil.ReadFile(input)
+  if err != nil {
+    return nil, err
+  }
+
+  size, _, _ := c.Format.pcm()
+  open := func(start time.Duration) (io.ReadCloser, error) {
+    i := samplesAt(start, 44100) * int64(size * 2)
+    if i > int64(len(b)) {
+      i = int64(len(b))
+    }
+    return ioutil.NopCloser(bytes.NewReader(b[i:])), nil
+  }
+  return newDecoder(44100, 2, "stereo", c, open)
+}
+
+func (m *MockFfmpeg) DecodeContext(ctx context.Context, input string,
+  c *DecodeConfig) (*Decoder, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.Decode(input, c)
+}
+
+func (m *MockFfmpeg) ExtractClip(input string, start, duration time.Duration,
+  output string, c *ClipConfig) (string, error) {
+
+  if c == nil {
+    c = &ClipConfig{}
+  }
+  t := c.Transcode
+  t.Input, t.Output, t.Duration = input, output, duration.Seconds()
+  return m.Transcode(&t)
+}
+
+func (m *MockFfmpeg) ExtractClipContext(ctx context.Context, input string,
+  start, duration time.Duration, output string,
+  c *ClipConfig) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.ExtractClip(input, start, duration, output, c)
+}
+
+func (m *MockFfmpeg) Crossfade(first, second, output string,
+  c *CrossfadeConfig) (string, error) {
+
+  return m.Concat([]string{ first, second }, output, nil)
+}
+
+func (m *MockFfmpeg) CrossfadeContext(ctx context.Context, first, second,
+  output string, c *CrossfadeConfig) (string, error) {
+
+  if err := ctx.Err(); err != nil {
+    return "", err
+  }
+  return m.Crossfade(first, second, output, c)
+}
+
+// reports any existing file as clean
+func (m *MockFfmpeg) Verify(path string) (*VerifyReport, error) {
+  _, err := os.Stat(path)
+  if err != nil {
+    return nil, err
+  }
+  return &VerifyReport{ Path: path, Issues: []VerifyIssue{}, Clean: true }, nil
+}
+
+func (m *MockFfmpeg) VerifyContext(ctx context.Context,
+  path string) (*VerifyReport, error) {
+
+  if err := ctx.Err(); err != nil {
+    return nil, err
+  }
+  return m.Verify(path)
+}
@@ -0,0 +1,375 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "time"
+  "errors"
+  "context"
+  "strconv"
+  "strings"
+  "path/filepath"
+)
+
+// target audio codec
+type Codec string
+
+const (
+  CodecMp3 Codec = "mp3"
+  CodecFlac Codec = "flac"
+  CodecOpus Codec = "opus"
+  CodecAac Codec = "aac"
+  CodecVorbis Codec = "vorbis"
+  CodecWav Codec = "wav"
+)
+
+// how tags are written for a container
+type tagStyle int
+
+const (
+  tagsId3 tagStyle = iota
+  tagsVorbis
+  tagsMp4
+  tagsRiff
+)
+
+// encoder, muxer & tagging details per codec
+type codecInfo struct {
+  encoder, format, ext string
+  tags tagStyle
+  artwork bool
+}
+
+var codecs = map[Codec]*codecInfo{
+  CodecMp3: { encoder: "libmp3lame", format: "mp3", ext: "mp3",
+    tags: tagsId3, artwork: true },
+  CodecFlac: { encoder: "flac", format: "flac", ext: "flac",
+    tags: tagsVorbis, artwork: true },
+  CodecOpus: { encoder: "libopus", format: "opus", ext: "opus",
+    tags: tagsVorbis },
+  CodecAac: { encoder: "aac", format: "ipod", ext: "m4a",
+    tags: tagsMp4, artwork: true },
+  CodecVorbis: { encoder: "libvorbis", format: "ogg", ext: "ogg",
+    tags: tagsVorbis },
+  CodecWav: { encoder: "pcm_s16le", format: "wav", ext: "wav",
+    tags: tagsRiff },
+}
+
+// codec by file extension
+var extCodecs = map[string]Codec{
+  "mp3": CodecMp3,
+  "flac": CodecFlac,
+  "ogg": CodecVorbis,
+  "opus": CodecOpus,
+  "m4a": CodecAac,
+  "mp4": CodecAac,
+  "wav": CodecWav,
+}
+
+// determine codec (& so container) for path from its extension
+func codecFor(path string) (Codec, bool) {
+  ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+  c, ok := extCodecs[ext]
+  return c, ok
+}
+
+// determine how tags are written for path from its extension
+func tagStyleFor(path string) (tagStyle, bool) {
+  c, ok := codecFor(path)
+  if !ok {
+    return tagsId3, false
+  }
+  return codecs[c].tags, true
+}
+
+// encoder quality settings, zero values use the encoder default
+type Quality struct {
+  // copy audio stream without re-encoding
+  Copy bool
+  // target bitrate in kbps: mp3 (cbr), opus, aac, vorbis
+  Bitrate int
+  // vbr level: mp3 V0-V9, vorbis 0-10, aac 1-5 (libfdk_aac vbr mode)
+  Vbr int
+  // flac compression level 0-12
+  Compression int
+}
+
+type TranscodeConfig struct {
+  Input, Output string
+  // name of a registered preset, overrides Codec & Quality when set
+  Preset string
+  Codec Codec
+  Quality Quality
+  // artwork is only embedded for mp3, flac & aac
+  Meta Metadata
+  // called as encoding advances
+  Progress ProgressFunc `json:"-"`
+  // input duration in seconds (ffprobe Format.Duration) used to compute
+  // percent complete; probed from Input when 0 and Progress is set
+  Duration float64
+  // remove leading & trailing silence detected with these settings
+  TrimSilence *SilenceConfig
+  // two-pass EBU R128 loudness normalization to target when set
+  Normalize *Loudness
+  // REPLAYGAIN_* tags to write (mp3, flac, opus & vorbis only)
+  ReplayGain *ReplayGain
+  // mono fold, channel extraction, swap or downmix
+  Channels *ChannelConfig
+  // sample rate & bit depth conversion, applied after other filters
+  Resample *ResampleConfig
+  // range of Input read, set by ExtractClip
+  start, length time.Duration
+}
+
+// convert audio to codec specified by config
+func (f *ffmpeg) Transcode(c *TranscodeConfig) (string, error) {
+  return f.TranscodeContext(context.Background(), c)
+}
+
+// convert audio to codec specified by config, aborting if ctx is done
+func (f *ffmpeg) TranscodeContext(ctx context.Context,
+  c *TranscodeConfig) (string, error) {
+
+  s, _, err := f.transcode(ctx, c)
+  return s, err
+}
+
+// transcode applying filters before any from config. returns loudness
+// measured first when normalizing
+func (f *ffmpeg) transcode(ctx context.Context, c *TranscodeConfig,
+  filters ...string) (string, *LoudnessStats, error) {
+
+  // fail before any measuring pass if the encoder or muxer is missing
+  a, err := transcodeArgs(c, filters...)
+  if err == nil {
+    err = f.require(ctx, a)
+  }
+  if err != nil {
+    return "", nil, err
+  }
+
+  if c.TrimSilence != nil {
+    s, err := f.DetectSilenceContext(ctx, c.Input, *c.TrimSilence)
+    if err != nil {
+      return "", nil, err
+    }
+
+    d := seconds(c.Duration)
+    if d == 0 {
+      d = seconds(probeDuration(ctx, c.Input))
+    }
+
+    if t := trimFilter(s, d); len(t) > 0 {
+      filters = append(filters, t)
+    }
+  }
+
+  if c.Channels != nil {
+    s, err := audioStream(ctx, c.Input)
+    if err != nil {
+      return "", nil, err
+    }
+    ch, err := c.Channels.filter(s.Channels, s.ChannelLayout)
+    if err != nil {
+      return "", nil, err
+    }
+    filters = append(filters, ch)
+  }
+
+  // sample rate out of the filters so far
+  rate := 0
+  if c.Normalize != nil || c.Resample != nil {
+    rate, err = sampleRate(ctx, c.Input)
+    if err != nil {
+      return "", nil, err
+    }
+  }
+
+  // measured after the filters above, as they change loudness
+  var stats *LoudnessStats
+  if c.Normalize != nil {
+    stats, err = f.measureLoudness(ctx, c.inputArgs(), *c.Normalize, filters)
+    if err != nil {
+      return "", nil, err
+    }
+
+    // loudnorm falls back to dynamic mode when the measured range or true
+    // peak exceed the target, which always outputs 192 kHz
+    filters = append(filters, c.Normalize.linearFilter(stats),
+      "aresample=" + strconv.Itoa(rate))
+  }
+
+  if c.Resample != nil {
+    r, err := f.resampleFilter(ctx, c.Resample, rate)
+    if err != nil {
+      return "", nil, err
+    }
+    if len(r) > 0 {
+      filters = append(filters, r)
+    }
+  }
+
+  if c.Progress != nil && c.Duration == 0 {
+    c.Duration = probeDuration(ctx, c.Input)
+  }
+
+  // output only appears once fully written
+  var s string
+  err = f.writeAtomic(c.Output, func(tmp string) error {
+    t := *c
+    t.Output = tmp
+
+    a, err := transcodeArgs(&t, filters...)
+    if err != nil {
+      return err
+    }
+
+    s, err = f.execProgress(ctx, c.Duration, c.Progress, a...)
+    return err
+  })
+
+  return s, stats, err
+}
+
+// input arguments, seeking accurately to the range read when set
+func (c *TranscodeConfig) inputArgs() []string {
+  a := []string{}
+  if c.start > 0 {
+    a = append(a, "-ss", fmt.Sprint(c.start.Seconds()))
+  }
+  if c.length > 0 {
+    a = append(a, "-t", fmt.Sprint(c.length.Seconds()))
+  }
+  return append(a, "-i", c.Input)
+}
+
+// codec & quality from preset if set, otherwise from config
+func (c *TranscodeConfig) codec() (Codec, Quality, error) {
+  codec, q := c.Codec, c.Quality
+  if len(c.Preset) > 0 {
+    p, err := LookupPreset(c.Preset)
+    if err != nil {
+      return "", Quality{}, err
+    }
+    codec, q = p.Codec, p.Quality
+  }
+
+  err := q.validate(codec)
+  if err == nil && c.Resample != nil {
+    err = c.Resample.validate(codec)
+  }
+  return codec, q, err
+}
+
+// build ffmpeg arguments for a transcode, applying audio filters in order
+func transcodeArgs(c *TranscodeConfig, filters ...string) ([]string, error) {
+  codec, q, err := c.codec()
+  if err != nil {
+    return []string{}, err
+  }
+  if q.Copy && len(filters) > 0 {
+    return []string{}, errors.New("audio filters require re-encoding, not copy")
+  }
+  info := codecs[codec]
+  if err := c.Meta.supported(info.tags); err != nil {
+    return []string{}, err
+  }
+
+  artwork := info.artwork && len(c.Meta.Artwork) > 0
+
+  a := c.inputArgs()
+  if artwork {
+    a = append(a, "-i", c.Meta.Artwork)
+  }
+
+  a = append(a, "-map", "0:a")
+  if len(filters) > 0 {
+    a = append(a, "-af", strings.Join(filters, ","))
+  }
+  if c.Resample != nil {
+    ca, err := c.Resample.codecArgs(codec, q)
+    if err != nil {
+      return []string{}, err
+    }
+    a = append(a, ca...)
+  } else {
+    a = append(a, codecArgs(codec, q)...)
+  }
+  a = append(a, metadataArgs(info.tags, c.Meta)...)
+  a = append(a, replayGainArgs(info.tags, c.ReplayGain)...)
+
+  // embedd album artwork
+  if artwork {
+    a = append(a, artworkArgs(info.tags, 1)...)
+  }
+
+  // mp4 must be fragmented when the output can not seek
+  if info.tags == tagsMp4 && strings.HasPrefix(c.Output, "pipe:") {
+    a = append(a, "-movflags", "+frag_keyframe+empty_moov")
+  }
+
+  return append(a, "-f", info.format, "-y", c.Output), nil
+}
+
+// audio encoder & quality arguments
+func codecArgs(codec Codec, q Quality) []string {
+  if q.Copy {
+    return []string{ "-c:a", "copy" }
+  }
+
+  info := codecs[codec]
+  a := []string{ "-c:a", info.encoder }
+
+  switch codec {
+  case CodecMp3:
+    if q.Bitrate > 0 {
+      a = append(a, "-b:a", bitrate(q.Bitrate))
+    } else {
+      a = append(a, "-qscale:a", strconv.Itoa(q.Vbr))
+    }
+  case CodecFlac:
+    if q.Compression > 0 {
+      a = append(a, "-compression_level", strconv.Itoa(q.Compression))
+    }
+  case CodecAac:
+    // native aac encoder has no usable vbr mode
+    if q.Vbr > 0 {
+      a = []string{ "-c:a", "libfdk_aac", "-vbr", strconv.Itoa(q.Vbr) }
+    } else if q.Bitrate > 0 {
+      a = append(a, "-b:a", bitrate(q.Bitrate))
+    }
+  case CodecOpus:
+    if q.Bitrate > 0 {
+      a = append(a, "-b:a", bitrate(q.Bitrate))
+    }
+  case CodecVorbis:
+    if q.Vbr > 0 {
+      a = append(a, "-qscale:a", strconv.Itoa(q.Vbr))
+    } else if q.Bitrate > 0 {
+      a = append(a, "-b:a", bitrate(q.Bitrate))
+    }
+  }
+
+  return a
+}
+
+// kbps as ffmpeg bitrate argument
+func bitrate(kbps int) string {
+  return strconv.Itoa(kbps) + "k"
+}
+
+// map input as front cover picture
+func artworkArgs(style tagStyle, input int) []string {
+  a := []string{ "-map", strconv.Itoa(input) + ":v", "-c:v", "copy" }
+
+  if style == tagsId3 {
+    return append(a, "-metadata:s:v", "title=Album cover",
+      "-metadata:s:v", "comment=Cover (Front)")
+  }
+
+  // flac & mp4 require the picture be flagged as attached
+  a = append(a, "-disposition:v", "attached_pic")
+  if style == tagsVorbis {
+    a = append(a, "-metadata:s:v", "comment=Cover (front)")
+  }
+  return a
+}
@@ -0,0 +1,163 @@
+package ffmpeg
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestTranscodeArgs(t *testing.T) {
+  meta := Metadata{ Artist: "Artist", Album: "Album", Disc: "1",
+    Track: "2", Title: "Title", Date: "2018-01-01", Artwork: "cover.jpg" }
+
+  tests := []struct {
+    config *TranscodeConfig
+    args string
+  }{
+    { config: &TranscodeConfig{ Input: "in.flac", Output: "out.mp3",
+      Codec: CodecMp3, Meta: meta },
+      args: "-i in.flac -i cover.jpg -map 0:a -c:a libmp3lame -qscale:a 0 " +
+        "-id3v2_version 4 -metadata artist=Artist -metadata album=Album " +
+        "-metadata disc=1 -metadata track=2 -metadata title=Title " +
+        "-metadata date=2018-01-01 -map 1:v -c:v copy " +
+        "-metadata:s:v title=Album cover -metadata:s:v comment=Cover (Front) " +
+        "-f mp3 -y out.mp3" },
+    { config: &TranscodeConfig{ Input: "in.wav", Output: "out.flac",
+      Codec: CodecFlac, Quality: Quality{ Compression: 8 }, Meta: meta },
+      args: "-i in.wav -i cover.jpg -map 0:a -c:a flac -compression_level 8 " +
+        "-metadata ARTIST=Artist -metadata ALBUM=Album -metadata DISCNUMBER=1 " +
+        "-metadata TRACKNUMBER=2 -metadata TITLE=Title " +
+        "-metadata DATE=2018-01-01 -map 1:v -c:v copy " +
+        "-disposition:v attached_pic -metadata:s:v comment=Cover (front) " +
+        "-f flac -y out.flac" },
+    { config: &TranscodeConfig{ Input: "in.flac", Output: "out.opus",
+      Codec: CodecOpus, Quality: Quality{ Bitrate: 128 }, Meta: meta },
+      args: "-i in.flac -map 0:a -c:a libopus -b:a 128k " +
+        "-metadata ARTIST=Artist -metadata ALBUM=Album -metadata DISCNUMBER=1 " +
+        "-metadata TRACKNUMBER=2 -metadata TITLE=Title " +
+        "-metadata DATE=2018-01-01 -f opus -y out.opus" },
+    { config: &TranscodeConfig{ Input: "in.flac", Output: "out.m4a",
+      Codec: CodecAac, Quality: Quality{ Vbr: 5 } },
+      args: "-i in.flac -map 0:a -c:a libfdk_aac -vbr 5 " +
+        "-metadata artist= -metadata album= -metadata disc= -metadata track= " +
+        "-metadata title= -metadata date= -f ipod -y out.m4a" },
+  }
+
+  for i := range tests {
+    a, err := transcodeArgs(tests[i].config)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if strings.Join(a, " ") != tests[i].args {
+      t.Errorf("Expected %v, got %v", tests[i].args, strings.Join(a, " "))
+    }
+  }
+
+  _, err := transcodeArgs(&TranscodeConfig{ Codec: "wma" })
+  if err == nil {
+    t.Errorf("Expected error for unsupported codec")
+  }
+}
+
+func TestTranscodeArgsPipe(t *testing.T) {
+  a, err := transcodeArgs(&TranscodeConfig{ Input: "pipe:0", Output: "pipe:1",
+    Codec: CodecAac, Quality: Quality{ Bitrate: 256 }, Meta: Metadata{
+    Artist: "Artist", Album: "Album", Title: "Title" } })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  exp := "-i pipe:0 -map 0:a -c:a aac -b:a 256k -metadata artist=Artist " +
+    "-metadata album=Album -metadata disc= -metadata track= " +
+    "-metadata title=Title -metadata date= " +
+    "-movflags +frag_keyframe+empty_moov -f ipod -y pipe:1"
+  if strings.Join(a, " ") != exp {
+    t.Errorf("Expected %v, got %v", exp, strings.Join(a, " "))
+  }
+}
+
+func TestTranscodeCleanup(t *testing.T) {
+  dir, err := ioutil.TempDir("", "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  // false stands in for a failing ffmpeg
+  f := &ffmpeg{ Bin: "false" }
+  _, err = f.ToMp3(&Mp3Config{ Input: "in.flac", Fix: true,
+    Output: filepath.Join(dir, "out.mp3") })
+  if err == nil {
+    t.Errorf("Expected error")
+  }
+
+  _, err = f.Transcode(&TranscodeConfig{ Input: "in.flac", Codec: CodecFlac,
+    Output: filepath.Join(dir, "out.flac") })
+  if err == nil {
+    t.Errorf("Expected error")
+  }
+
+  files, _ := ioutil.ReadDir(dir)
+  if len(files) != 0 {
+    t.Errorf("Expected no files left behind, got %v", len(files))
+  }
+}
+
+// put an ffprobe first on PATH reporting a 10 minute stereo flac stream at
+// rate, returns a func restoring PATH
+func fakeFfprobe(t *testing.T, rate int) func() {
+  dir, err := ioutil.TempDir("", "ffprobe")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  data := fmt.Sprintf(`{"streams":[{"index":0,"codec_name":"flac",` +
+    `"codec_type":"audio","channels":2,"channel_layout":"stereo",` +
+    `"sample_rate":"%d"}],"format":{"duration":"600.0"}}`, rate)
+  err = ioutil.WriteFile(filepath.Join(dir, "ffprobe"),
+    []byte("#!/bin/sh\necho '" + data + "'\n"), 0755)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  path := os.Getenv("PATH")
+  os.Setenv("PATH", dir + string(os.PathListSeparator) + path)
+  return func() {
+    os.Setenv("PATH", path)
+    os.RemoveAll(dir)
+  }
+}
+
+func TestTranscodeNormalizeRate(t *testing.T) {
+  defer fakeFfprobe(t, 44100)()
+
+  tests := []struct {
+    resample *ResampleConfig
+    filters string
+  }{
+    { nil, ":linear=true,aresample=44100" },
+    { &ResampleConfig{ SampleRate: 44100 }, ":linear=true,aresample=44100" },
+    { &ResampleConfig{ SampleRate: 22050 },
+      ":linear=true,aresample=44100,aresample=osr=22050" },
+  }
+
+  for i := range tests {
+    d := NewDryRun()
+    _, err := d.Transcode(&TranscodeConfig{ Input: "in.flac",
+      Output: "out.flac", Codec: CodecFlac, Normalize: &DefaultLoudness,
+      Resample: tests[i].resample })
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    // loudnorm's 192 kHz fallback is resampled back to source rate
+    c := d.Commands()
+    a := strings.Join(c[len(c)-2], " ")
+    if !strings.Contains(a, tests[i].filters + " -c:a flac") {
+      t.Errorf("Expected filters ending %v, got %v", tests[i].filters, a)
+    }
+  }
+}
@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+  "io"
+  "os"
+  "fmt"
+  "time"
+  "bytes"
+  "errors"
+  "regexp"
+  "context"
+  "strconv"
+  "strings"
+  "encoding/hex"
+)
+
+var (
+  // [flac @ 0x55d0c4a3c2c0] [error] invalid sync code
+  regexpVerifyIssue = regexp.MustCompile(
+    `^(?:\[([^\]\s@]+)[^\]]*\] )?\[(warning|error|fatal|panic)\] (.*)$`)
+  regexpVerifyTime = regexp.MustCompile(`time=(\d+):(\d\d):(\d\d(?:\.\d+)?)`)
+  regexpVerifyMd5 = regexp.MustCompile(`MD5=([0-9a-f]{32})`)
+)
+
+// decode warning or error
+type VerifyIssue struct {
+  // decode position last reported before the issue, approximate
+  Time time.Duration
+  // "warning", "error", "fatal" or "panic"
+  Level string
+  // reporting component, e.g. "flac" or "mp3float"
+  Source string
+  Message string
+}
+
+type VerifyReport struct {
+  Path string
+  // decode warnings & errors in order
+  Issues []VerifyIssue
+  // flac only: STREAMINFO holds an MD5 of the audio which was compared
+  // against the decoded audio
+  Md5Checked bool
+  Md5Match bool
+  // no decode errors & MD5 matched when checked. warnings are allowed
+  Clean bool
+}
+
+// fully decode path, reporting decode errors & warnings and, for flac,
+// whether the decoded audio matches the STREAMINFO MD5
+func (f *ffmpeg) Verify(path string) (*VerifyReport, error) {
+  return f.VerifyContext(context.Background(), path)
+}
+
+// verify path, aborting if ctx is done. returns a report for input ffmpeg
+// rejects as invalid data; other failures are errors
+func (f *ffmpeg) VerifyContext(ctx context.Context,
+  path string) (*VerifyReport, error) {
+
+  // flac md5 is of samples packed in as many bytes as bits per sample need
+  var md5 []byte
+  pcm := ""
+  if c, _ := codecFor(path); c == CodecFlac {
+    bits, sum, err := flacStreamInfo(path)
+    if err != nil {
+      return nil, err
+    }
+
+    switch bits {
+    case 8:
+      pcm = "pcm_s8"
+    case 16, 24, 32:
+      pcm = fmt.Sprintf("pcm_s%dle", bits)
+    }
+    if len(pcm) > 0 && !bytes.Equal(sum, make([]byte, 16)) {
+      md5 = sum
+    } else {
+      pcm = ""
+    }
+  }
+
+  var out bytes.Buffer
+  stderr, err := f.run(ctx, nil, &out, verifyArgs(path, pcm))
+
+  var e *ExecError
+  if err != nil && !(errors.As(err, &e) && e.Cause == CauseInvalidData) {
+    return nil, err
+  }
+
+  r := &VerifyReport{ Path: path, Issues: parseVerifyIssues(stderr) }
+  if err == nil && md5 != nil && f.dryRun == nil {
+    m := regexpVerifyMd5.FindStringSubmatch(out.String())
+    if m == nil {
+      return nil, errors.New("md5 not found in ffmpeg output")
+    }
+    r.Md5Checked = true
+    r.Md5Match = m[1] == hex.EncodeToString(md5)
+  }
+
+  r.Clean = err == nil && (!r.Md5Checked || r.Md5Match)
+  for _, i := range r.Issues {
+    if i.Level != "warning" {
+      r.Clean = false
+    }
+  }
+  return r, nil
+}
+
+// decode audio of path to the null muxer, or to md5 of pcm samples
+func verifyArgs(path, pcm string) []string {
+  a := []string{ "-hide_banner", "-loglevel", "level+warning", "-stats",
+    "-i", path, "-map", "0:a" }
+  if len(pcm) > 0 {
+    return append(a, "-c:a", pcm, "-f", "md5", "-")
+  }
+  return append(a, "-f", "null", "-")
+}
+
+// issues in stderr, timed by the stats line written before each
+func parseVerifyIssues(stderr string) []VerifyIssue {
+  issues := []VerifyIssue{}
+
+  var t time.Duration
+  lines := strings.FieldsFunc(stderr, func(r rune) bool {
+    return r == '\n' || r == '\r'
+  })
+  for _, l := range lines {
+    if m := regexpVerifyTime.FindStringSubmatch(l); m != nil {
+      h, _ := strconv.Atoi(m[1])
+      mins, _ := strconv.Atoi(m[2])
+      s, _ := strconv.ParseFloat(m[3], 64)
+      t = time.Duration(h) * time.Hour + time.Duration(mins) * time.Minute +
+        seconds(s)
+      continue
+    }
+
+    m := regexpVerifyIssue.FindStringSubmatch(strings.TrimSpace(l))
+    if m != nil {
+      issues = append(issues, VerifyIssue{ Time: t, Level: m[2], Source: m[1],
+        Message: m[3] })
+    }
+  }
+  return issues
+}
+
+// bits per sample & md5 from the STREAMINFO block of a flac file
+func flacStreamInfo(path string) (int, []byte, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return 0, nil, err
+  }
+  defer file.Close()
+
+  // 4 byte marker, 4 byte block header & 34 byte STREAMINFO
+  b := make([]byte, 42)
+  _, err = io.ReadFull(file, b[:10])
+  if err != nil {
+    return 0, nil, fmt.Errorf("%v: %v", path, err)
+  }
+
+  // skip id3v2 tag, its size is 4 syncsafe bytes
+  start := int64(0)
+  if string(b[:3]) == "ID3" {
+    start = 10 + (int64(b[6]) << 21 | int64(b[7]) << 14 |
+      int64(b[8]) << 7 | int64(b[9]))
+    if b[5] & 0x10 != 0 {
+      start += 10
+    }
+  }
+
+  _, err = file.ReadAt(b, start)
+  if err != nil {
+    return 0, nil, fmt.Errorf("%v: %v", path, err)
+  }
+  if string(b[:4]) != "fLaC" || b[4] & 0x7f != 0 {
+    return 0, nil, fmt.Errorf("%v: no flac STREAMINFO", path)
+  }
+
+  // STREAMINFO: block sizes (4), frame sizes (6), then 20 bits sample
+  // rate, 3 bits channels - 1, 5 bits bits per sample - 1
+  info := b[8:]
+  bits := int((info[12] & 0x01) << 4 | info[13] >> 4) + 1
+  return bits, info[18:34], nil
+}
@@ -0,0 +1,88 @@
+package ffmpeg
+
+import (
+  "os"
+  "time"
+  "reflect"
+  "testing"
+  "io/ioutil"
+  "encoding/hex"
+  "path/filepath"
+)
+
+func TestParseVerifyIssues(t *testing.T) {
+  stderr := "Input #0, flac, from 'in.flac':\n" +
+    "size=N/A time=00:00:10.50 bitrate=N/A speed= 501x    \r" +
+    "[flac @ 0x55d0c4a3c2c0] [error] invalid sync code\n" +
+    "[flac @ 0x55d0c4a3c2c0] [warning] decode_frame() failed\n" +
+    "size=N/A time=01:02:03.25 bitrate=N/A speed= 499x    \r" +
+    "[fatal] Conversion failed!\n"
+
+  e := []VerifyIssue{
+    { 10500 * time.Millisecond, "error", "flac", "invalid sync code" },
+    { 10500 * time.Millisecond, "warning", "flac", "decode_frame() failed" },
+    { time.Hour + 2 * time.Minute + 3250 * time.Millisecond, "fatal", "",
+      "Conversion failed!" },
+  }
+
+  i := parseVerifyIssues(stderr)
+  if !reflect.DeepEqual(i, e) {
+    t.Errorf("Expected %v, got %v", e, i)
+  }
+}
+
+func TestVerify(t *testing.T) {
+  dir, err := ioutil.TempDir("", "verify")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  // stands in for ffmpeg: no capabilities, a warning & md5 of audio
+  bin := filepath.Join(dir, "ffmpeg")
+  err = ioutil.WriteFile(bin, []byte("#!/bin/sh\n" +
+    "case \"$2\" in -version|-encoders|-muxers|-filters) exit 1;; esac\n" +
+    "echo '[flac @ 0x1] [warning] skipping padding' >&2\n" +
+    "echo MD5=00112233445566778899aabbccddeeff\n"), 0755)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  md5, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+  tests := []struct {
+    sum []byte
+    checked, match, clean bool
+  }{
+    { md5, true, true, true },
+    { make([]byte, 16), false, false, true },
+    { append([]byte{ 1 }, md5[1:]...), true, false, false },
+  }
+
+  f := &ffmpeg{ Bin: bin }
+  for i := range tests {
+    // STREAMINFO of 16 bit stereo
+    info := make([]byte, 34)
+    info[12], info[13] = 0x02, 0xf0
+    copy(info[18:], tests[i].sum)
+
+    path := filepath.Join(dir, "in.flac")
+    b := append([]byte{ 'f', 'L', 'a', 'C', 0x80, 0, 0, 34 }, info...)
+    err = ioutil.WriteFile(path, b, 0644)
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    r, err := f.Verify(path)
+    if err != nil {
+      t.Fatal(err)
+    }
+    if r.Md5Checked != tests[i].checked || r.Md5Match != tests[i].match ||
+      r.Clean != tests[i].clean {
+      t.Errorf("Expected checked %v, match %v, clean %v, got %+v",
+        tests[i].checked, tests[i].match, tests[i].clean, r)
+    }
+    if len(r.Issues) != 1 || r.Issues[0].Level != "warning" {
+      t.Errorf("Expected 1 warning, got %v", r.Issues)
+    }
+  }
+}
@@ -0,0 +1,215 @@
+package ffmpeg
+
+import (
+  "fmt"
+  "math"
+  "errors"
+  "context"
+  "strconv"
+  "strings"
+  "encoding/binary"
+)
+
+type WaveformConfig struct {
+  // image size in pixels, 1800x300 if 0
+  Width, Height int
+  // color per channel, e.g. "#1e90ff" or "white"
+  Colors []string
+  // amplitude scale: lin, log, sqrt or cbrt
+  Scale string
+  // draw each channel separately
+  SplitChannels bool
+}
+
+type SpectrogramConfig struct {
+  // image size in pixels (excluding legend), 1024x512 if 0
+  Width, Height int
+  // color scheme: channel, intensity, rainbow, magma, viridis, ...
+  Color string
+  // intensity scale: lin, sqrt, cbrt, log, 4thrt or 5thrt
+  Scale string
+  // draw each channel separately
+  SplitChannels bool
+  // draw time & frequency axes
+  Legend bool
+}
+
+// render waveform of input as png
+func (f *ffmpeg) Waveform(input, output string, c *WaveformConfig) error {
+  return f.WaveformContext(context.Background(), input, output, c)
+}
+
+// render waveform of input as png, aborting if ctx is done
+func (f *ffmpeg) WaveformContext(ctx context.Context, input, output string,
+  c *WaveformConfig) error {
+
+  if c == nil {
+    c = &WaveformConfig{}
+  }
+
+  _, err := f.ExecContext(ctx, imageArgs(input, output, c.filter())...)
+  return err
+}
+
+// render spectrogram of input as png
+func (f *ffmpeg) Spectrogram(input, output string,
+  c *SpectrogramConfig) error {
+
+  return f.SpectrogramContext(context.Background(), input, output, c)
+}
+
+// render spectrogram of input as png, aborting if ctx is done
+func (f *ffmpeg) SpectrogramContext(ctx context.Context, input, output string,
+  c *SpectrogramConfig) error {
+
+  if c == nil {
+    c = &SpectrogramConfig{}
+  }
+
+  _, err := f.ExecContext(ctx, imageArgs(input, output, c.filter())...)
+  return err
+}
+
+// n peak amplitudes (0-1) across the duration of input, the highest absolute
+// sample of any channel in each slice. json encodes as a plain array
+func (f *ffmpeg) Peaks(input string, n int) ([]float64, error) {
+  return f.PeaksContext(context.Background(), input, n)
+}
+
+// n peak amplitudes across input, aborting if ctx is done
+func (f *ffmpeg) PeaksContext(ctx context.Context, input string,
+  n int) ([]float64, error) {
+
+  if n < 1 {
+    return nil, errors.New("number of peaks must be positive")
+  }
+
+  s, err := audioStream(ctx, input)
+  if err != nil {
+    return nil, err
+  }
+  rate, err := strconv.Atoi(s.SampleRate)
+  if err != nil || s.Channels < 1 {
+    return nil, fmt.Errorf("unknown sample rate or channels for %v", input)
+  }
+
+  d := probeDuration(ctx, input)
+  if d <= 0 {
+    return nil, fmt.Errorf("unknown duration for %v", input)
+  }
+
+  // samples (per channel) in each peak, last may be shorter
+  per := int64(math.Ceil(d * float64(rate) / float64(n)))
+  w := &peaksWriter{ channels: s.Channels, per: per,
+    peaks: make([]float64, 0, n) }
+
+  _, err = f.run(ctx, nil, w, []string{ "-i", input, "-map", "0:a:0",
+    "-f", "s16le", "-c:a", "pcm_s16le", "-" })
+  if err != nil {
+    return nil, err
+  }
+
+  p := w.flush()
+
+  // duration is an estimate, pad or trim to exactly n
+  for len(p) < n {
+    p = append(p, 0)
+  }
+  return p[:n], nil
+}
+
+func (c *WaveformConfig) filter() string {
+  w, h := size(c.Width, c.Height, 1800, 300)
+  a := []string{ fmt.Sprintf("s=%dx%d", w, h) }
+
+  if len(c.Colors) > 0 {
+    a = append(a, "colors=" + strings.Join(c.Colors, "|"))
+  }
+  if len(c.Scale) > 0 {
+    a = append(a, "scale=" + c.Scale)
+  }
+  if c.SplitChannels {
+    a = append(a, "split_channels=1")
+  }
+  return "showwavespic=" + strings.Join(a, ":")
+}
+
+func (c *SpectrogramConfig) filter() string {
+  w, h := size(c.Width, c.Height, 1024, 512)
+  a := []string{ fmt.Sprintf("s=%dx%d", w, h) }
+
+  if c.SplitChannels {
+    a = append(a, "mode=separate")
+  }
+  if len(c.Color) > 0 {
+    a = append(a, "color=" + c.Color)
+  }
+  if len(c.Scale) > 0 {
+    a = append(a, "scale=" + c.Scale)
+  }
+
+  legend := "0"
+  if c.Legend {
+    legend = "1"
+  }
+  return "showspectrumpic=" + strings.Join(append(a, "legend=" + legend), ":")
+}
+
+// width & height, or defaults when unset
+func size(w, h, dw, dh int) (int, int) {
+  if w <= 0 {
+    w = dw
+  }
+  if h <= 0 {
+    h = dh
+  }
+  return w, h
+}
+
+// render single png frame from audio with filter
+func imageArgs(input, output, filter string) []string {
+  return []string{ "-i", input, "-filter_complex", "[0:a]" + filter,
+    "-frames:v", "1", "-c:v", "png", "-f", "image2", "-y", output }
+}
+
+// reduces interleaved s16le samples to peaks
+type peaksWriter struct {
+  channels int
+  per, count int64
+  max float64
+  peaks []float64
+  buf []byte
+}
+
+func (w *peaksWriter) Write(b []byte) (int, error) {
+  w.buf = append(w.buf, b...)
+
+  // whole frames (a sample for every channel)
+  frame := 2 * w.channels
+  n := len(w.buf) / frame * frame
+
+  for i := 0; i < n; i += frame {
+    for c := 0; c < w.channels; c++ {
+      s := math.Abs(float64(int16(binary.LittleEndian.Uint16(w.buf[i+c*2:]))))
+      w.max = math.Max(w.max, s / 32768)
+    }
+
+    w.count++
+    if w.count == w.per {
+      w.peaks = append(w.peaks, w.max)
+      w.count, w.max = 0, 0
+    }
+  }
+
+  w.buf = w.buf[n:]
+  return len(b), nil
+}
+
+// peaks including any partial final slice
+func (w *peaksWriter) flush() []float64 {
+  if w.count > 0 {
+    w.peaks = append(w.peaks, w.max)
+    w.count, w.max = 0, 0
+  }
+  return w.peaks
+}
@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+  "strings"
+  "testing"
+  "encoding/binary"
+)
+
+func TestVisualFilters(t *testing.T) {
+  tests := []struct {
+    filter, expected string
+  }{
+    { (&WaveformConfig{}).filter(), "showwavespic=s=1800x300" },
+    { (&WaveformConfig{ Width: 800, Height: 100, Scale: "log",
+      Colors: []string{ "#ff0000", "white" }, SplitChannels: true }).filter(),
+      "showwavespic=s=800x100:colors=#ff0000|white:scale=log:split_channels=1" },
+    { (&SpectrogramConfig{}).filter(), "showspectrumpic=s=1024x512:legend=0" },
+    { (&SpectrogramConfig{ Color: "magma", Scale: "log", SplitChannels: true,
+      Legend: true }).filter(),
+      "showspectrumpic=s=1024x512:mode=separate:color=magma:scale=log:legend=1" },
+  }
+
+  for i := range tests {
+    if tests[i].filter != tests[i].expected {
+      t.Errorf("Expected %v, got %v", tests[i].expected, tests[i].filter)
+    }
+  }
+}
+
+func TestPeaksWriter(t *testing.T) {
+  // stereo frames, 2 per peak
+  samples := []int16{ 100, -16384, 0, 50, -32768, 0, 8192, 0, 0, 16384 }
+  b := make([]byte, len(samples) * 2)
+  for i := range samples {
+    binary.LittleEndian.PutUint16(b[i*2:], uint16(samples[i]))
+  }
+
+  w := &peaksWriter{ channels: 2, per: 2 }
+  // split mid sample
+  _, _ = w.Write(b[:5])
+  _, _ = w.Write(b[5:])
+
+  p := w.flush()
+  expected := []float64{ 0.5, 1, 0.5 }
+  if len(p) != len(expected) {
+    t.Fatalf("Expected %v, got %v", expected, p)
+  }
+  for i := range expected {
+    if p[i] != expected[i] {
+      t.Errorf("Expected %v, got %v", expected, p)
+    }
+  }
+}
+
+func TestPeaks(t *testing.T) {
+  defer fakeFfprobe(t, 44100)()
+
+  d := NewDryRun()
+  p, err := d.Peaks("in.flac", 10)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(p) != 10 {
+    t.Errorf("Expected 10 peaks, got %d", len(p))
+  }
+
+  // only the probed stream is decoded
+  c := d.Commands()
+  if len(c) != 1 || !strings.Contains(strings.Join(c[0], " "),
+    "-i in.flac -map 0:a:0 ") {
+    t.Errorf("Expected first audio stream decoded, got %v", c)
+  }
+}
@@ -1,13 +1,16 @@
 package ffprobe
 
 import (
+  "fmt"
   "bytes"
+  "context"
   "os/exec"
   "encoding/json"
 )
 
 type Ffprober interface {
   GetData(filePath string) (*Data, error)
+  GetDataContext(ctx context.Context, filePath string) (*Data, error)
   EmbeddedImage() (int, int, bool)
 }
 
@@ -48,6 +51,19 @@ type Stream struct {
   Width              int         `json:"width"`
   Height             int         `json:"height"`
   PixFmt             string      `json:"pix_fmt"`
+  Disposition        *Disposition `json:"disposition"`
+  Tags               *StreamTags `json:"tags"`
+}
+
+type Disposition struct {
+  Default            int         `json:"default"`
+  AttachedPic        int         `json:"attached_pic"`
+}
+
+type StreamTags struct {
+  Title              string      `json:"title"`
+  // picture type of embedded artwork, e.g. "Cover (front)"
+  Comment            string      `json:"comment"`
 }
 
 type Format struct {
@@ -88,16 +104,27 @@ func New() (*ffprobe, error) {
 }
 
 func (f *ffprobe) GetData(filePath string) (*Data, error) {
+  return f.GetDataContext(context.Background(), filePath)
+}
+
+// probe filePath, killing ffprobe if ctx is canceled or its deadline passes.
+// on cancellation the returned error wraps ctx.Err()
+func (f *ffprobe) GetDataContext(ctx context.Context,
+  filePath string) (*Data, error) {
+
   data := &Data{}
 
-  cmd := exec.Command(
-    f.Bin, "-v", "quiet", "-print_format", "json",
+  cmd := exec.CommandContext(
+    ctx, f.Bin, "-v", "quiet", "-print_format", "json",
     "-show_streams", "-show_format", filePath,
   )
   var out bytes.Buffer
   cmd.Stdout = &out
 
   err := cmd.Run()
+  if ctx.Err() != nil {
+    return data, fmt.Errorf("ffprobe: %w", ctx.Err())
+  }
   if err != nil {
     return data, err
   }
@@ -0,0 +1,41 @@
+package ffprobe
+
+import (
+  "os"
+  "time"
+  "errors"
+  "context"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+)
+
+func TestGetDataContext(t *testing.T) {
+  dir, err := ioutil.TempDir("", "ffprobe")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  // ffprobe that never finishes
+  bin := filepath.Join(dir, "ffprobe")
+  err = ioutil.WriteFile(bin, []byte("#!/bin/sh\nexec sleep 5\n"), 0755)
+  if err != nil {
+    t.Fatal(err)
+  }
+  f := &ffprobe{ Bin: bin }
+
+  ctx, cancel := context.WithTimeout(context.Background(),
+    200 * time.Millisecond)
+  defer cancel()
+
+  // child is killed at the deadline, not left to finish
+  start := time.Now()
+  _, err = f.GetDataContext(ctx, "in.flac")
+  if !errors.Is(err, context.DeadlineExceeded) {
+    t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+  }
+  if d := time.Since(start); d > 2 * time.Second {
+    t.Errorf("Expected ffprobe killed at deadline, ran %v", d)
+  }
+}
@@ -1,6 +1,7 @@
 package ffprobe
 
 import (
+  "context"
   "io/ioutil"
   "encoding/json"
 )
@@ -32,3 +33,12 @@ func (m *MockFfprobe) GetData(filePath string) (*Data, error) {
 
   return d, nil
 }
+
+func (m *MockFfprobe) GetDataContext(ctx context.Context,
+  filePath string) (*Data, error) {
+
+  if err := ctx.Err(); err != nil {
+    return &Data{ Format: &Format{ Tags: &Tags{} } }, err
+  }
+  return m.GetData(filePath)
+}